@@ -0,0 +1,433 @@
+package main
+
+import (
+	"archive/zip"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/adi290491/chirpy/internal/auth"
+	"github.com/adi290491/chirpy/internal/database"
+	"github.com/adi290491/chirpy/internal/jobs"
+	"github.com/google/uuid"
+)
+
+type ExportProfile struct {
+	ID          uuid.UUID `json:"id"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+	Email       string    `json:"email"`
+	Handle      string    `json:"handle"`
+	IsChirpyRed bool      `json:"is_chirpy_red"`
+}
+
+type ExportSession struct {
+	CreatedAt *time.Time `json:"created_at"`
+	LastUsed  *time.Time `json:"last_used"`
+	ExpiresAt time.Time  `json:"expires_at"`
+	RevokedAt *time.Time `json:"revoked_at"`
+	UserAgent string     `json:"user_agent"`
+	IP        string     `json:"ip"`
+}
+
+// exportPageSize is how many chirps an export loads at a time.
+const exportPageSize = 100
+
+func (c *apiConfig) DeleteAccount(w http.ResponseWriter, r *http.Request) {
+	userID := currentUserID(r)
+
+	type requestParams struct {
+		Password string `json:"password"`
+	}
+
+	var req requestParams
+	decoder := json.NewDecoder(r.Body)
+	err := decoder.Decode(&req)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "invalid request body", err)
+		return
+	}
+
+	user, err := c.db.GetUserByID(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "error while fetching user", err)
+		return
+	}
+
+	match, err := auth.CheckPasswordHash(req.Password, user.HashedPassword)
+	if err != nil || !match {
+		respondWithError(w, http.StatusUnauthorized, "incorrect password", err)
+		return
+	}
+
+	err = c.withTx(r.Context(), func(q *database.Queries) error {
+		media, err := q.ListMediaByUser(r.Context(), userID)
+		if err != nil {
+			return err
+		}
+
+		// chirps with replies stay behind as tombstones without an author, as
+		// deleting one by itself does; the lock keeps new replies out until
+		// the rest are gone
+		err = q.LockChirpsByUser(r.Context(), userID)
+		if err != nil {
+			return err
+		}
+		_, err = q.OrphanRepliedChirpsByUser(r.Context(), userID)
+		if err != nil {
+			return err
+		}
+
+		// other chirps, refresh tokens, follows, likes and media rows all cascade
+		err = q.DeleteUserByID(r.Context(), userID)
+		if err != nil || len(media) == 0 {
+			return err
+		}
+
+		// the files go once the rows are gone for good
+		job := deleteBlobsJob{}
+		for _, m := range media {
+			job.Keys = append(job.Keys, m.StorageKey, m.ThumbnailKey)
+		}
+		_, err = jobs.Enqueue(r.Context(), q, jobDeleteBlobs, job)
+		return err
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "error while deleting account", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusNoContent, nil)
+}
+
+func (c *apiConfig) ExportAccount(w http.ResponseWriter, r *http.Request) {
+	userID := currentUserID(r)
+
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = "json"
+	}
+
+	if format != "json" && format != "zip" {
+		respondWithError(w, http.StatusBadRequest, "invalid format", fmt.Errorf("format must be json or zip, got %q", format))
+		return
+	}
+
+	export, err := c.startAccountExport(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "error while exporting account", err)
+		return
+	}
+
+	filename := fmt.Sprintf("chirpy-export-%s", export.ExportedAt.Format("20060102T150405Z"))
+
+	if format == "json" {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.json"`, filename))
+	} else {
+		w.Header().Set("Content-Type", "application/zip")
+		w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.zip"`, filename))
+	}
+	w.WriteHeader(http.StatusOK)
+
+	// headers are gone by now, so failures can only be logged
+	if format == "json" {
+		err = c.writeExportJSON(r.Context(), w, export)
+	} else {
+		err = c.writeExportZip(r.Context(), w, export)
+	}
+	if err != nil {
+		log.Printf("could not stream export for user %s: %v", userID, err)
+	}
+}
+
+// accountExport is what an export loads before the response starts: the
+// small sections, and the media rows whose files are copied later. Chirps
+// are read and written a page at a time as the export streams.
+type accountExport struct {
+	ExportedAt time.Time
+	Profile    ExportProfile
+	Sessions   []ExportSession
+
+	userID    uuid.UUID
+	mediaRows []database.Medium
+}
+
+func (c *apiConfig) startAccountExport(ctx context.Context, userID uuid.UUID) (accountExport, error) {
+	user, err := c.db.GetUserByID(ctx, userID)
+	if err != nil {
+		return accountExport{}, err
+	}
+
+	sessionRows, err := c.db.ListRefreshTokensByUser(ctx, userID)
+	if err != nil {
+		return accountExport{}, err
+	}
+
+	mediaRows, err := c.db.ListMediaByUser(ctx, userID)
+	if err != nil {
+		return accountExport{}, err
+	}
+
+	export := accountExport{
+		ExportedAt: time.Now().UTC(),
+		Profile: ExportProfile{
+			ID:          user.ID,
+			CreatedAt:   user.CreatedAt,
+			UpdatedAt:   user.UpdatedAt,
+			Email:       user.Email,
+			Handle:      user.Handle,
+			IsChirpyRed: user.IsChirpyRed,
+		},
+		Sessions:  []ExportSession{},
+		userID:    userID,
+		mediaRows: mediaRows,
+	}
+
+	for _, row := range sessionRows {
+		session := ExportSession{
+			ExpiresAt: row.ExpiresAt,
+			UserAgent: row.UserAgent,
+			IP:        row.IpAddress,
+		}
+		if row.CreatedAt.Valid {
+			session.CreatedAt = &row.CreatedAt.Time
+		}
+		if row.UpdatedAt.Valid {
+			session.LastUsed = &row.UpdatedAt.Time
+		}
+		if row.RevokedAt.Valid {
+			session.RevokedAt = &row.RevokedAt.Time
+		}
+		export.Sessions = append(export.Sessions, session)
+	}
+
+	return export, nil
+}
+
+// forEachExportChirp calls fn with each of the user's chirps, oldest first,
+// loading and hydrating them exportPageSize at a time.
+func (c *apiConfig) forEachExportChirp(ctx context.Context, userID uuid.UUID, fn func(Chirp) error) error {
+	params := database.ListChirpsAscParams{
+		AuthorID: uuid.NullUUID{UUID: userID, Valid: true},
+		Limit:    exportPageSize,
+	}
+
+	for {
+		rows, err := c.db.ListChirpsAsc(ctx, params)
+		if err != nil {
+			return err
+		}
+
+		page := make([]Chirp, 0, len(rows))
+		for _, row := range rows {
+			page = append(page, chirpFromDB(row))
+		}
+
+		err = c.hydrateChirps(ctx, uuid.NullUUID{UUID: userID, Valid: true}, chirpRefs(page)...)
+		if err != nil {
+			return err
+		}
+
+		for _, chirp := range page {
+			if err := fn(chirp); err != nil {
+				return err
+			}
+		}
+
+		if len(rows) < exportPageSize {
+			return nil
+		}
+
+		last := rows[len(rows)-1]
+		params.CursorCreatedAt = sql.NullTime{Time: last.CreatedAt, Valid: true}
+		params.CursorID = uuid.NullUUID{UUID: last.ID, Valid: true}
+	}
+}
+
+// jsonArray writes a JSON array one element at a time.
+type jsonArray struct {
+	w      io.Writer
+	indent string
+	n      int
+}
+
+func (a *jsonArray) add(v any) error {
+	var data []byte
+	var err error
+	if a.indent == "" {
+		data, err = json.Marshal(v)
+	} else {
+		data, err = json.MarshalIndent(v, a.indent, a.indent)
+	}
+	if err != nil {
+		return err
+	}
+
+	sep := ","
+	if a.n == 0 {
+		sep = "["
+	}
+	if a.indent != "" {
+		sep += "\n" + a.indent
+	}
+	a.n++
+
+	if _, err := io.WriteString(a.w, sep); err != nil {
+		return err
+	}
+	_, err = a.w.Write(data)
+	return err
+}
+
+func (a *jsonArray) close() error {
+	end := "]"
+	switch {
+	case a.n == 0:
+		end = "[]"
+	case a.indent != "":
+		end = "\n]"
+	}
+
+	_, err := io.WriteString(a.w, end)
+	return err
+}
+
+// writeExportJSON streams the export as one JSON document:
+// {"exported_at", "profile", "chirps", "sessions", "media"}.
+func (c *apiConfig) writeExportJSON(ctx context.Context, w io.Writer, export accountExport) error {
+	head, err := json.Marshal(struct {
+		ExportedAt time.Time     `json:"exported_at"`
+		Profile    ExportProfile `json:"profile"`
+	}{export.ExportedAt, export.Profile})
+	if err != nil {
+		return err
+	}
+
+	// the head object without its closing brace, continued field by field
+	if _, err := w.Write(head[:len(head)-1]); err != nil {
+		return err
+	}
+
+	if _, err := io.WriteString(w, `,"chirps":`); err != nil {
+		return err
+	}
+	chirps := &jsonArray{w: w}
+	err = c.forEachExportChirp(ctx, export.userID, func(chirp Chirp) error {
+		return chirps.add(chirp)
+	})
+	if err != nil {
+		return err
+	}
+	if err := chirps.close(); err != nil {
+		return err
+	}
+
+	sessions, err := json.Marshal(export.Sessions)
+	if err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintf(w, `,"sessions":%s,"media":`, sessions); err != nil {
+		return err
+	}
+
+	media := &jsonArray{w: w}
+	for _, row := range export.mediaRows {
+		if err := media.add(mediaFromDB(row)); err != nil {
+			return err
+		}
+	}
+	if err := media.close(); err != nil {
+		return err
+	}
+
+	_, err = io.WriteString(w, "}\n")
+	return err
+}
+
+// writeExportZip streams one JSON file per section plus the original image
+// files under media/.
+func (c *apiConfig) writeExportZip(ctx context.Context, w io.Writer, export accountExport) error {
+	zw := zip.NewWriter(w)
+
+	sections := []struct {
+		name  string
+		write func(a *jsonArray) error
+	}{
+		{"chirps.json", func(a *jsonArray) error {
+			return c.forEachExportChirp(ctx, export.userID, func(chirp Chirp) error {
+				return a.add(chirp)
+			})
+		}},
+		{"sessions.json", func(a *jsonArray) error {
+			for _, session := range export.Sessions {
+				if err := a.add(session); err != nil {
+					return err
+				}
+			}
+			return nil
+		}},
+		{"media.json", func(a *jsonArray) error {
+			for _, row := range export.mediaRows {
+				if err := a.add(mediaFromDB(row)); err != nil {
+					return err
+				}
+			}
+			return nil
+		}},
+	}
+
+	f, err := zw.Create("profile.json")
+	if err != nil {
+		return err
+	}
+	encoder := json.NewEncoder(f)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(export.Profile); err != nil {
+		return err
+	}
+
+	for _, section := range sections {
+		f, err := zw.Create(section.name)
+		if err != nil {
+			return err
+		}
+
+		a := &jsonArray{w: f, indent: "  "}
+		if err := section.write(a); err != nil {
+			return err
+		}
+		if err := a.close(); err != nil {
+			return err
+		}
+		if _, err := io.WriteString(f, "\n"); err != nil {
+			return err
+		}
+	}
+
+	for _, row := range export.mediaRows {
+		blob, err := c.storage.Open(ctx, row.StorageKey)
+		if err != nil {
+			return err
+		}
+
+		f, err := zw.Create("media/" + row.StorageKey)
+		if err != nil {
+			blob.Close()
+			return err
+		}
+
+		_, err = io.Copy(f, blob)
+		blob.Close()
+		if err != nil {
+			return err
+		}
+	}
+
+	return zw.Close()
+}
@@ -0,0 +1,184 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"database/sql/driver"
+	"encoding/json"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/adi290491/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+// exportStore holds an account with more chirps than fit in one page.
+type exportStore struct {
+	user   database.User
+	chirps []database.Chirp
+	media  []database.Medium
+	pages  int
+}
+
+func newExportStore() *exportStore {
+	s := &exportStore{user: testUser()}
+
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i := range exportPageSize + 1 {
+		at := start.Add(time.Duration(i) * time.Minute)
+		s.chirps = append(s.chirps, database.Chirp{
+			ID:        uuid.New(),
+			CreatedAt: at,
+			UpdatedAt: at,
+			Body:      "chirp",
+			UserID:    s.user.ID,
+		})
+	}
+
+	s.media = append(s.media, database.Medium{
+		ID:           uuid.New(),
+		UserID:       s.user.ID,
+		ContentType:  "image/png",
+		StorageKey:   "a.png",
+		ThumbnailKey: "a_thumb.png",
+		Width:        10,
+		Height:       20,
+		CreatedAt:    start,
+	})
+
+	return s
+}
+
+func (s *exportStore) queries() map[string]fakeQuery {
+	return map[string]fakeQuery{
+		"GetUserByID": func([]driver.Value) ([][]driver.Value, error) {
+			return modelRows(s.user), nil
+		},
+		"ListRefreshTokensByUser": func([]driver.Value) ([][]driver.Value, error) {
+			return nil, nil
+		},
+		"ListMediaByUser": func([]driver.Value) ([][]driver.Value, error) {
+			return modelRows(s.media...), nil
+		},
+		"ListChirpsAsc": func(args []driver.Value) ([][]driver.Value, error) {
+			s.pages++
+
+			// args: author, cursor created_at, cursor id, limit
+			start := 0
+			if args[2] != nil {
+				for i, chirp := range s.chirps {
+					if chirp.ID.String() == args[2] {
+						start = i + 1
+					}
+				}
+			}
+			end := min(start+int(args[3].(int64)), len(s.chirps))
+			return modelRows(s.chirps[start:end]...), nil
+		},
+		"GetChirpStats": func([]driver.Value) ([][]driver.Value, error) {
+			return nil, nil
+		},
+		"ListMediaForChirps": func([]driver.Value) ([][]driver.Value, error) {
+			return nil, nil
+		},
+	}
+}
+
+func TestExportAccountJSON(t *testing.T) {
+	store := newExportStore()
+	c := newTestConfig(t, store.queries())
+
+	export, err := c.startAccountExport(context.Background(), store.user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if err := c.writeExportJSON(context.Background(), &buf, export); err != nil {
+		t.Fatal(err)
+	}
+
+	var got struct {
+		ExportedAt time.Time       `json:"exported_at"`
+		Profile    ExportProfile   `json:"profile"`
+		Chirps     []Chirp         `json:"chirps"`
+		Sessions   []ExportSession `json:"sessions"`
+		Media      []Media         `json:"media"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("export is not valid JSON: %v\n%s", err, buf.String())
+	}
+
+	if got.Profile.ID != store.user.ID || got.Profile.Email != testEmail {
+		t.Errorf("unexpected profile %+v", got.Profile)
+	}
+	if len(got.Chirps) != len(store.chirps) {
+		t.Fatalf("expected %d chirps, got %d", len(store.chirps), len(got.Chirps))
+	}
+	for i, chirp := range got.Chirps {
+		if chirp.ID != store.chirps[i].ID {
+			t.Fatalf("chirp %d: expected %s, got %s", i, store.chirps[i].ID, chirp.ID)
+		}
+	}
+	if store.pages != 2 {
+		t.Errorf("expected the chirps to be read in 2 pages, got %d", store.pages)
+	}
+	if got.Sessions == nil || len(got.Sessions) != 0 {
+		t.Errorf("expected an empty list of sessions, got %v", got.Sessions)
+	}
+	if len(got.Media) != 1 || got.Media[0].ID != store.media[0].ID {
+		t.Errorf("unexpected media %+v", got.Media)
+	}
+}
+
+func TestExportAccountZip(t *testing.T) {
+	store := newExportStore()
+	store.media = nil
+	c := newTestConfig(t, store.queries())
+
+	export, err := c.startAccountExport(context.Background(), store.user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if err := c.writeExportZip(context.Background(), &buf, export); err != nil {
+		t.Fatal(err)
+	}
+
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("export is not a zip: %v", err)
+	}
+
+	sections := map[string]int{}
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var items []json.RawMessage
+		if f.Name == "profile.json" {
+			continue
+		}
+		if err := json.Unmarshal(data, &items); err != nil {
+			t.Fatalf("%s is not a JSON array: %v\n%s", f.Name, err, data)
+		}
+		sections[f.Name] = len(items)
+	}
+
+	want := map[string]int{"chirps.json": len(store.chirps), "sessions.json": 0, "media.json": 0}
+	for name, n := range want {
+		if got, ok := sections[name]; !ok || got != n {
+			t.Errorf("expected %s to hold %d items, got %d", name, n, got)
+		}
+	}
+}
@@ -0,0 +1,205 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/adi290491/chirpy/internal/auth"
+	"github.com/adi290491/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+const (
+	maxAPITokenNameLength = 100
+	maxAPITokenLifetime   = 365
+)
+
+var errUnknownAPIToken = errors.New("API token is invalid, revoked or expired")
+
+type APIToken struct {
+	ID         uuid.UUID  `json:"id"`
+	Name       string     `json:"name"`
+	Scopes     []string   `json:"scopes"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+	Token      string     `json:"token,omitempty"`
+}
+
+func apiTokenFromDB(row database.ApiToken) APIToken {
+	token := APIToken{
+		ID:        row.ID,
+		Name:      row.Name,
+		Scopes:    row.Scopes,
+		CreatedAt: row.CreatedAt,
+	}
+	if row.LastUsedAt.Valid {
+		token.LastUsedAt = &row.LastUsedAt.Time
+	}
+	if row.ExpiresAt.Valid {
+		token.ExpiresAt = &row.ExpiresAt.Time
+	}
+	return token
+}
+
+// authenticateAPIToken turns a personal access token into claims limited to
+// the token's scopes.
+func (c *apiConfig) authenticateAPIToken(ctx context.Context, token string) (*auth.Claims, error) {
+	row, err := c.db.GetActiveAPIToken(ctx, auth.HashAPIToken(token))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, errUnknownAPIToken
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := c.db.TouchAPIToken(ctx, row.ID); err != nil {
+		log.Printf("could not record API token use: %v", err)
+	}
+
+	scopes := make([]auth.Scope, 0, len(row.Scopes))
+	for _, s := range row.Scopes {
+		scopes = append(scopes, auth.Scope(s))
+	}
+
+	claims := &auth.Claims{
+		UserID:     row.UserID,
+		APITokenID: row.ID,
+		Scopes:     scopes,
+		IssuedAt:   row.CreatedAt,
+	}
+	if row.ExpiresAt.Valid {
+		claims.ExpiresAt = row.ExpiresAt.Time
+	}
+
+	return claims, nil
+}
+
+func (c *apiConfig) CreateAPIToken(w http.ResponseWriter, r *http.Request) {
+	userID := currentUserID(r)
+
+	type requestParams struct {
+		Name          string   `json:"name"`
+		Scopes        []string `json:"scopes"`
+		ExpiresInDays *int     `json:"expires_in_days"`
+	}
+
+	var req requestParams
+	decoder := json.NewDecoder(r.Body)
+	err := decoder.Decode(&req)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "invalid request body", err)
+		return
+	}
+
+	name := strings.TrimSpace(req.Name)
+	if name == "" || len(name) > maxAPITokenNameLength {
+		respondWithError(w, http.StatusBadRequest, "invalid token name", fmt.Errorf("name must be 1 to %d characters", maxAPITokenNameLength))
+		return
+	}
+
+	if len(req.Scopes) == 0 {
+		respondWithError(w, http.StatusBadRequest, "at least one scope is required", nil)
+		return
+	}
+
+	scopes := []string{}
+	for _, s := range req.Scopes {
+		scope, err := auth.ParseScope(s)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "invalid scope", err)
+			return
+		}
+		if !slices.Contains(scopes, string(scope)) {
+			scopes = append(scopes, string(scope))
+		}
+	}
+
+	var expiresAt sql.NullTime
+	if req.ExpiresInDays != nil {
+		days := *req.ExpiresInDays
+		if days < 1 || days > maxAPITokenLifetime {
+			respondWithError(w, http.StatusBadRequest, "invalid expiry", fmt.Errorf("expires_in_days must be between 1 and %d", maxAPITokenLifetime))
+			return
+		}
+		expiresAt = sql.NullTime{Time: time.Now().UTC().AddDate(0, 0, days), Valid: true}
+	}
+
+	token, err := auth.MakeAPIToken()
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "error while generating token", err)
+		return
+	}
+
+	row, err := c.db.CreateAPIToken(r.Context(), database.CreateAPITokenParams{
+		UserID:    userID,
+		Name:      name,
+		TokenHash: auth.HashAPIToken(token),
+		Scopes:    scopes,
+		ExpiresAt: expiresAt,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "error while creating token", err)
+		return
+	}
+
+	c.audit(r.Context(), r, auditAPITokenCreated, uuid.NullUUID{UUID: userID, Valid: true}, "")
+
+	// the token itself is only ever shown here
+	resp := apiTokenFromDB(row)
+	resp.Token = token
+	respondWithJSON(w, http.StatusCreated, resp)
+}
+
+func (c *apiConfig) ListAPITokens(w http.ResponseWriter, r *http.Request) {
+	userID := currentUserID(r)
+
+	rows, err := c.db.ListAPITokensByUser(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "error while fetching tokens", err)
+		return
+	}
+
+	tokens := []APIToken{}
+	for _, row := range rows {
+		tokens = append(tokens, apiTokenFromDB(row))
+	}
+
+	respondWithJSON(w, http.StatusOK, tokens)
+}
+
+func (c *apiConfig) RevokeAPIToken(w http.ResponseWriter, r *http.Request) {
+	userID := currentUserID(r)
+
+	tokenID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "invalid token ID", err)
+		return
+	}
+
+	revoked, err := c.db.RevokeAPIToken(r.Context(), database.RevokeAPITokenParams{
+		ID:     tokenID,
+		UserID: userID,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "error while revoking token", err)
+		return
+	}
+
+	if revoked == 0 {
+		respondWithError(w, http.StatusNotFound, "token not found", nil)
+		return
+	}
+
+	c.audit(r.Context(), r, auditAPITokenRevoked, uuid.NullUUID{UUID: userID, Valid: true}, "")
+
+	respondWithJSON(w, http.StatusNoContent, nil)
+}
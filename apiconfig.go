@@ -1,6 +1,8 @@
 package main
 
 import (
+	"context"
+	"database/sql"
 	"errors"
 	"fmt"
 	"log"
@@ -10,14 +12,29 @@ import (
 
 	"github.com/adi290491/chirpy/internal/auth"
 	"github.com/adi290491/chirpy/internal/database"
+	"github.com/adi290491/chirpy/internal/jobs"
+	"github.com/adi290491/chirpy/internal/mail"
+	"github.com/adi290491/chirpy/internal/moderation"
+	"github.com/adi290491/chirpy/internal/storage"
+	"github.com/google/uuid"
 )
 
 type apiConfig struct {
-	fileServerHits atomic.Int32
-	db             *database.Queries
-	JWT_SECRET     string
-	PLATFORM       string
-	API_KEY        string
+	fileServerHits       atomic.Int32
+	conn                 *sql.DB
+	db                   *database.Queries
+	wordList             *moderation.WordList
+	moderator            moderation.Filter
+	storage              storage.Storage
+	keyring              *auth.Keyring
+	mailer               mail.Mailer
+	publicURL            string
+	requireVerifiedEmail bool
+	polkaSecret          string
+	webhookClient        *http.Client
+	jobs                 *jobs.Runner
+	PLATFORM             string
+	API_KEY              string
 }
 
 func (c *apiConfig) middlewareMetricsInc(next http.Handler) http.Handler {
@@ -61,6 +78,91 @@ func (c *apiConfig) ResetFileServerHits(w http.ResponseWriter, r *http.Request)
 	w.WriteHeader(http.StatusOK)
 }
 
+const refreshTokenLifetime = 60 * 24 * time.Hour
+
+var (
+	errInvalidRefreshToken = errors.New("refresh token is invalid or has expired")
+	errRefreshTokenReused  = errors.New("refresh token was already used")
+)
+
+// issueRefreshToken stores a fresh refresh token in the given family. Login
+// starts a new family; every rotation continues the one it came from.
+func issueRefreshToken(ctx context.Context, q *database.Queries, userID, familyID uuid.UUID, client sessionClient) (string, error) {
+	token, err := auth.MakeRefreshToken()
+	if err != nil {
+		return "", err
+	}
+
+	_, err = q.CreateRefreshToken(ctx, database.CreateRefreshTokenParams{
+		TokenHash: auth.HashRefreshToken(token),
+		UserID:    userID,
+		ExpiresAt: time.Now().Add(refreshTokenLifetime),
+		FamilyID:  familyID,
+		UserAgent: client.UserAgent,
+		IpAddress: client.IP,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return token, nil
+}
+
+// rotateRefreshToken swaps a live refresh token for a new one in the same
+// family. A token that was already rotated means two parties hold the same
+// session, so the whole family is revoked and errRefreshTokenReused returned.
+func (c *apiConfig) rotateRefreshToken(ctx context.Context, token string, client sessionClient) (database.User, string, error) {
+	var user database.User
+	var newToken string
+	reused := false
+
+	err := c.withTx(ctx, func(q *database.Queries) error {
+		// the row lock makes concurrent refreshes of one token queue up, so
+		// only the first can rotate it
+		current, err := q.GetRefreshTokenForUpdate(ctx, auth.HashRefreshToken(token))
+		if errors.Is(err, sql.ErrNoRows) {
+			return errInvalidRefreshToken
+		}
+		if err != nil {
+			return err
+		}
+
+		if current.RotatedAt.Valid {
+			reused = true
+			return q.RevokeRefreshTokenFamily(ctx, current.FamilyID)
+		}
+
+		if current.RevokedAt.Valid || !current.ExpiresAt.After(time.Now()) {
+			return errInvalidRefreshToken
+		}
+
+		err = q.RotateRefreshToken(ctx, current.TokenHash)
+		if err != nil {
+			return err
+		}
+
+		newToken, err = issueRefreshToken(ctx, q, current.UserID, current.FamilyID, client)
+		if err != nil {
+			return err
+		}
+
+		// the new access token carries the user's current role, so role
+		// changes take effect on the next refresh
+		user, err = q.GetUserByID(ctx, current.UserID)
+		return err
+	})
+	if err != nil {
+		return database.User{}, "", err
+	}
+
+	// the family revocation has to commit, so reuse is reported only after it
+	if reused {
+		return database.User{}, "", errRefreshTokenReused
+	}
+
+	return user, newToken, nil
+}
+
 func (c *apiConfig) RefreshToken(w http.ResponseWriter, r *http.Request) {
 	token, err := auth.GetBearerToken(r.Header)
 
@@ -70,17 +172,29 @@ func (c *apiConfig) RefreshToken(w http.ResponseWriter, r *http.Request) {
 	}
 
 	type RefreshTokenResponse struct {
-		Token string `json:"token"`
+		Token        string `json:"token"`
+		RefreshToken string `json:"refresh_token"`
 	}
 
-	user, err := c.db.GetUserFromRefreshToken(r.Context(), token)
+	user, refreshToken, err := c.rotateRefreshToken(r.Context(), token, clientFromRequest(r))
 
-	if err != nil {
+	if errors.Is(err, errRefreshTokenReused) {
+		log.Printf("refresh token reuse detected, revoked its family")
 		respondWithError(w, http.StatusUnauthorized, "refresh token is invalid or has expired", err)
 		return
 	}
 
-	newToken, err := auth.MakeJWT(user.ID, c.JWT_SECRET, expirationTime*time.Hour)
+	if errors.Is(err, errInvalidRefreshToken) {
+		respondWithError(w, http.StatusUnauthorized, "refresh token is invalid or has expired", err)
+		return
+	}
+
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "error while refreshing token", err)
+		return
+	}
+
+	newToken, err := auth.MakeJWT(user.ID, auth.Role(user.Role), c.keyring, expirationTime*time.Hour)
 
 	if err != nil {
 		respondWithError(w, http.StatusInternalServerError, "error while refreshing token", err)
@@ -88,7 +202,8 @@ func (c *apiConfig) RefreshToken(w http.ResponseWriter, r *http.Request) {
 	}
 
 	respondWithJSON(w, http.StatusOK, RefreshTokenResponse{
-		Token: newToken,
+		Token:        newToken,
+		RefreshToken: refreshToken,
 	})
 }
 
@@ -100,7 +215,7 @@ func (c *apiConfig) RevokeToken(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = c.db.UpdateRefreshToken(r.Context(), token)
+	err = c.db.UpdateRefreshToken(r.Context(), auth.HashRefreshToken(token))
 	if err != nil {
 		respondWithError(w, http.StatusInternalServerError, "error while revoking refresh token", err)
 		return
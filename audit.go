@@ -0,0 +1,40 @@
+package main
+
+import (
+	"context"
+	"log"
+	"net/http"
+
+	"github.com/adi290491/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+// Audit log events.
+const (
+	auditLoginSucceeded = "login.succeeded"
+	auditLoginFailed    = "login.failed"
+	auditLoginBlocked   = "login.blocked"
+	auditMFAFailed      = "login.mfa_failed"
+	auditAccountLocked  = "account.locked"
+	auditPasswordReset  = "password.reset"
+
+	auditAPITokenCreated = "api_token.created"
+	auditAPITokenRevoked = "api_token.revoked"
+)
+
+// audit records a security event. A failed write is logged rather than
+// failing the request it describes.
+func (c *apiConfig) audit(ctx context.Context, r *http.Request, event string, userID uuid.NullUUID, email string) {
+	client := clientFromRequest(r)
+
+	err := c.db.CreateAuditEvent(ctx, database.CreateAuditEventParams{
+		Event:     event,
+		UserID:    userID,
+		Email:     email,
+		IpAddress: client.IP,
+		UserAgent: client.UserAgent,
+	})
+	if err != nil {
+		log.Printf("could not write audit event %s: %v", event, err)
+	}
+}
@@ -1,72 +1,129 @@
 package main
 
 import (
+	"context"
+	"database/sql"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"log"
 	"net/http"
-	"sort"
-	"strings"
 	"time"
+	"unicode/utf8"
 
-	"github.com/adi290491/chirpy/internal/auth"
 	"github.com/adi290491/chirpy/internal/database"
+	"github.com/adi290491/chirpy/internal/jobs"
+	"github.com/adi290491/chirpy/internal/moderation"
 	"github.com/google/uuid"
 )
 
-var (
-	profanes = map[string]bool{
-		"kerfuffle": true,
-		"sharbert":  true,
-		"fornax":    true,
+type Chirp struct {
+	ID           uuid.UUID  `json:"id"`
+	Body         string     `json:"body"`
+	UserId       uuid.UUID  `json:"user_id"`
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+	InReplyTo    *uuid.UUID `json:"in_reply_to,omitempty"`
+	Deleted      bool       `json:"deleted,omitempty"`
+	LikeCount    int64      `json:"like_count"`
+	RechirpCount int64      `json:"rechirp_count"`
+	LikedByMe    *bool      `json:"liked_by_me,omitempty"`
+	Media        []Media    `json:"media,omitempty"`
+}
+
+var errChirpLimitReached = errors.New("hourly chirp limit reached")
+
+// chirpFromDB maps a database row onto the API payload. Deleted chirps that
+// still have replies are kept as tombstones with an empty body.
+func chirpFromDB(row database.Chirp) Chirp {
+	chirp := Chirp{
+		ID:        row.ID,
+		CreatedAt: row.CreatedAt,
+		UpdatedAt: row.UpdatedAt,
+		Body:      row.Body,
+		UserId:    row.UserID,
+		Deleted:   row.DeletedAt.Valid,
 	}
-)
+	if row.InReplyTo.Valid {
+		parentID := row.InReplyTo.UUID
+		chirp.InReplyTo = &parentID
+	}
+	return chirp
+}
 
-type Chirp struct {
-	ID        uuid.UUID `json:"id"`
-	Body      string    `json:"body"`
-	UserId    uuid.UUID `json:"user_id"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+// hydrateChirps loads everything a Chirp payload carries beyond its row:
+// counters, liked_by_me and attached media.
+func (c *apiConfig) hydrateChirps(ctx context.Context, viewer uuid.NullUUID, chirps ...*Chirp) error {
+	if err := c.attachChirpStats(ctx, viewer, chirps...); err != nil {
+		return err
+	}
+	return c.attachChirpMediaInfo(ctx, chirps...)
 }
 
-func validateChirp(body string) (string, error) {
+// validateChirp enforces the author's length limit, counted in characters
+// rather than bytes, and runs the body through the moderation pipeline.
+// Callers must check res.Rejected before storing res.Body.
+func (c *apiConfig) validateChirp(body string, maxLength int) (moderation.Result, error) {
 
-	if len(body) > maxChirpLength {
-		return "", errors.New("Chirp is too long")
+	if utf8.RuneCountInString(body) > maxLength {
+		return moderation.Result{}, fmt.Errorf("the limit is %d characters", maxLength)
 	}
 
-	return cleanupChirps(body), nil
+	return moderation.Run(c.moderator, body), nil
 }
 
-func cleanupChirps(body string) string {
-	tmp := strings.Split(body, " ")
-	for i, word := range tmp {
-		if profanes[strings.ToLower(word)] {
-			tmp[i] = "****"
-		}
+// createChirp stores a moderated chirp with its media, moderation flag and
+// tags, and queues its chirp.created webhooks. It is shared by posting and
+// by publishing scheduled chirps.
+func createChirp(ctx context.Context, q *database.Queries, userID uuid.UUID, moderated moderation.Result, inReplyTo uuid.NullUUID, mediaIDs []uuid.UUID) (database.Chirp, error) {
+	chirp, err := q.CreateChirp(ctx, database.CreateChirpParams{
+		Body:      moderated.Body,
+		UserID:    userID,
+		InReplyTo: inReplyTo,
+	})
+	if err != nil {
+		return database.Chirp{}, err
 	}
-	return strings.Join(tmp, " ")
+
+	if err := attachChirpMedia(ctx, q, userID, chirp.ID, mediaIDs); err != nil {
+		return database.Chirp{}, err
+	}
+
+	if err := flagChirp(ctx, q, chirp.ID, moderated); err != nil {
+		return database.Chirp{}, err
+	}
+
+	if err := indexChirpTags(ctx, q, chirp); err != nil {
+		return database.Chirp{}, err
+	}
+
+	if err := emitEvent(ctx, q, eventChirpCreated, userID, chirpFromDB(chirp)); err != nil {
+		return database.Chirp{}, err
+	}
+
+	return chirp, nil
 }
 
 func (c *apiConfig) CreateChirp(w http.ResponseWriter, r *http.Request) {
 
-	authorization, err := auth.GetBearerToken(r.Header)
+	userID := currentUserID(r)
 
+	user, limits, err := c.entitlementsFor(r.Context(), userID)
 	if err != nil {
-		respondWithError(w, http.StatusUnauthorized, "", err)
+		respondWithError(w, http.StatusNotFound, "error while fetching user", err)
 		return
 	}
 
-	uuid, err := auth.ValidateJWT(authorization, c.JWT_SECRET)
-
-	if err != nil {
-		respondWithError(w, http.StatusUnauthorized, "invalid token", err)
+	if c.requireVerifiedEmail && !user.EmailVerifiedAt.Valid {
+		respondWithError(w, http.StatusForbidden, "verify your email address before chirping", nil)
 		return
 	}
 
 	type requestParams struct {
-		Body string `json:"body"`
+		Body      string      `json:"body"`
+		InReplyTo *uuid.UUID  `json:"in_reply_to"`
+		MediaIDs  []uuid.UUID `json:"media_ids"`
+		PublishAt *time.Time  `json:"publish_at"`
 	}
 
 	decoder := json.NewDecoder(r.Body)
@@ -80,29 +137,116 @@ func (c *apiConfig) CreateChirp(w http.ResponseWriter, r *http.Request) {
 
 	log.Printf("Request: %+v", req)
 
-	cleanedBody, err := validateChirp(req.Body)
+	moderated, err := c.validateChirp(req.Body, limits.MaxChirpLength)
 	if err != nil {
 		respondWithError(w, http.StatusBadRequest, "Chirp is too long", err)
 		return
 	}
 
-	chirp, err := c.db.CreateChirp(r.Context(), database.CreateChirpParams{
-		Body:   cleanedBody,
-		UserID: uuid,
+	if moderated.Rejected {
+		respondWithRejection(w, moderated)
+		return
+	}
+
+	if len(req.MediaIDs) > maxMediaPerChirp {
+		respondWithError(w, http.StatusBadRequest, "too many attachments", fmt.Errorf("a chirp can have at most %d media", maxMediaPerChirp))
+		return
+	}
+
+	var inReplyTo uuid.NullUUID
+	if req.InReplyTo != nil {
+		parent, err := c.db.GetChirpById(r.Context(), *req.InReplyTo)
+		if err != nil {
+			respondWithError(w, http.StatusNotFound, "parent chirp not found", err)
+			return
+		}
+		inReplyTo = uuid.NullUUID{UUID: parent.ID, Valid: true}
+	}
+
+	if req.PublishAt != nil {
+		if len(req.MediaIDs) > 0 {
+			respondWithError(w, http.StatusBadRequest, "scheduled chirps cannot have media", nil)
+			return
+		}
+		c.scheduleChirp(w, r, limits, req.Body, inReplyTo, *req.PublishAt)
+		return
+	}
+
+	var chirp database.Chirp
+	err = c.withTx(r.Context(), func(q *database.Queries) error {
+		// concurrent posts by the user wait here, so each counts the last
+		err := q.LockUser(r.Context(), userID)
+		if err != nil {
+			return err
+		}
+
+		recent, err := q.CountChirpsByUserSince(r.Context(), database.CountChirpsByUserSinceParams{
+			UserID:    userID,
+			CreatedAt: time.Now().UTC().Add(-time.Hour),
+		})
+		if err != nil {
+			return err
+		}
+
+		if recent >= int64(limits.ChirpsPerHour) {
+			return errChirpLimitReached
+		}
+
+		chirp, err = createChirp(r.Context(), q, userID, moderated, inReplyTo, req.MediaIDs)
+		return err
 	})
 
+	if errors.Is(err, errChirpLimitReached) {
+		respondWithError(w, http.StatusTooManyRequests, "hourly chirp limit reached", fmt.Errorf("%s members may post %d chirps an hour", limits.Tier, limits.ChirpsPerHour))
+		return
+	}
+
+	if errors.Is(err, errInvalidMedia) {
+		respondWithError(w, http.StatusBadRequest, "invalid media_ids", err)
+		return
+	}
+
 	if err != nil {
 		respondWithError(w, http.StatusInternalServerError, "Failed to create a chirp", err)
 		return
 	}
 
-	respondWithJSON(w, http.StatusCreated, Chirp{
-		ID:        chirp.ID,
-		CreatedAt: chirp.CreatedAt,
-		UpdatedAt: chirp.UpdatedAt,
-		Body:      chirp.Body,
-		UserId:    chirp.UserID,
-	})
+	resp := chirpFromDB(chirp)
+	err = c.hydrateChirps(r.Context(), uuid.NullUUID{UUID: userID, Valid: true}, &resp)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "error while fetching chirp", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusCreated, resp)
+}
+
+type ChirpPage struct {
+	Chirps     []Chirp `json:"chirps"`
+	NextCursor string  `json:"next_cursor,omitempty"`
+}
+
+// respondWithChirpPage writes one page of chirps. rows holds up to limit+1
+// entries; the extra row only signals that a next page exists.
+func (c *apiConfig) respondWithChirpPage(w http.ResponseWriter, r *http.Request, rows []database.Chirp, limit int32) {
+	page := ChirpPage{Chirps: []Chirp{}}
+	if len(rows) > int(limit) {
+		rows = rows[:limit]
+		last := rows[len(rows)-1]
+		page.NextCursor = encodeCursor(last.CreatedAt, last.ID)
+	}
+
+	for _, row := range rows {
+		page.Chirps = append(page.Chirps, chirpFromDB(row))
+	}
+
+	err := c.hydrateChirps(r.Context(), viewerID(r), chirpRefs(page.Chirps)...)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "error while fetching chirp details", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, page)
 }
 
 func (c *apiConfig) GetAllChirps(w http.ResponseWriter, r *http.Request) {
@@ -110,43 +254,57 @@ func (c *apiConfig) GetAllChirps(w http.ResponseWriter, r *http.Request) {
 	authorId := r.URL.Query().Get("author_id")
 	sortOrder := r.URL.Query().Get("sort")
 
-	var res []database.Chirp
-	var err error
-	if authorId == "" {
-		res, err = c.db.GetAllChirps(r.Context())
-	} else {
+	if sortOrder != "" && sortOrder != "asc" && sortOrder != "desc" {
+		respondWithError(w, http.StatusBadRequest, "invalid sort order", errors.New("sort must be asc or desc"))
+		return
+	}
+
+	limit, err := parseLimit(r.URL.Query().Get("limit"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "invalid limit", err)
+		return
+	}
+
+	var authorID uuid.NullUUID
+	if authorId != "" {
 		userID, err := uuid.Parse(authorId)
 		if err != nil {
-			respondWithError(w, http.StatusInternalServerError, "error while parsing author id", err)
+			respondWithError(w, http.StatusBadRequest, "error while parsing author id", err)
 			return
 		}
-		res, err = c.db.GetAllChirpsByUserId(r.Context(), userID)
+		authorID = uuid.NullUUID{UUID: userID, Valid: true}
 	}
 
+	cursorCreatedAt, cursorID, err := parseCursor(r.URL.Query().Get("cursor"))
 	if err != nil {
-		respondWithError(w, http.StatusInternalServerError, "error while fetching all chirps", err)
+		respondWithError(w, http.StatusBadRequest, "invalid cursor", err)
+		return
 	}
 
-	sort.Slice(res, func(i, j int) bool {
-		if sortOrder == "desc" {
-			return res[j].CreatedAt.Before(res[i].CreatedAt)
-		} else {
-			return res[i].CreatedAt.Before(res[j].CreatedAt)
-		}
-	})
-
-	var chirps []Chirp
-	for _, row := range res {
-		chirps = append(chirps, Chirp{
-			ID:        row.ID,
-			CreatedAt: row.CreatedAt,
-			UpdatedAt: row.UpdatedAt,
-			Body:      row.Body,
-			UserId:    row.UserID,
+	// fetch one extra row so we know whether another page follows
+	var res []database.Chirp
+	if sortOrder == "desc" {
+		res, err = c.db.ListChirpsDesc(r.Context(), database.ListChirpsDescParams{
+			AuthorID:        authorID,
+			CursorCreatedAt: cursorCreatedAt,
+			CursorID:        cursorID,
+			Limit:           limit + 1,
 		})
+	} else {
+		res, err = c.db.ListChirpsAsc(r.Context(), database.ListChirpsAscParams{
+			AuthorID:        authorID,
+			CursorCreatedAt: cursorCreatedAt,
+			CursorID:        cursorID,
+			Limit:           limit + 1,
+		})
+	}
+
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "error while fetching all chirps", err)
+		return
 	}
 
-	respondWithJSON(w, http.StatusOK, chirps)
+	c.respondWithChirpPage(w, r, res, limit)
 }
 
 func (c *apiConfig) GetChirpById(w http.ResponseWriter, r *http.Request) {
@@ -164,34 +322,110 @@ func (c *apiConfig) GetChirpById(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	respondWithJSON(w, http.StatusOK, Chirp{
-		ID:        chirp.ID,
-		CreatedAt: chirp.CreatedAt,
-		UpdatedAt: chirp.UpdatedAt,
-		Body:      chirp.Body,
-		UserId:    chirp.UserID,
-	})
+	resp := chirpFromDB(chirp)
+	err = c.hydrateChirps(r.Context(), viewerID(r), &resp)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "error while fetching chirp details", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, resp)
+}
+
+type chirpDeletedEvent struct {
+	ID     uuid.UUID `json:"id"`
+	UserID uuid.UUID `json:"user_id"`
 }
 
 func (c *apiConfig) DeleteChirp(w http.ResponseWriter, r *http.Request) {
-	authorization, err := auth.GetBearerToken(r.Header)
+	userID := currentUserID(r)
+
+	chirpID, err := uuid.Parse(r.PathValue("chirpID"))
 
 	if err != nil {
-		respondWithError(w, http.StatusUnauthorized, "", err)
+		respondWithError(w, http.StatusForbidden, "could not parse chirp ID", err)
 		return
 	}
 
-	userID, err := auth.ValidateJWT(authorization, c.JWT_SECRET)
+	chirp, err := c.db.GetChirpById(r.Context(), chirpID)
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "error while fetching chirp", err)
+		return
+	}
+
+	if chirp.UserID != userID {
+		respondWithError(w, http.StatusForbidden, "you cannot delete another user's chirp", nil)
+		return
+	}
 
+	err = c.withTx(r.Context(), func(q *database.Queries) error {
+		// a reply's foreign key check waits on this lock, so none can be
+		// added between counting the replies and acting on the count
+		_, err := q.GetChirpByIdForUpdate(r.Context(), chirpID)
+		if err != nil {
+			return err
+		}
+
+		// media goes with the chirp, even when a tombstone stays behind
+		media, err := q.DeleteMediaForChirp(r.Context(), chirpID)
+		if err != nil {
+			return err
+		}
+
+		if len(media) > 0 {
+			job := deleteBlobsJob{}
+			for _, m := range media {
+				job.Keys = append(job.Keys, m.StorageKey, m.ThumbnailKey)
+			}
+			_, err = jobs.Enqueue(r.Context(), q, jobDeleteBlobs, job)
+			if err != nil {
+				return err
+			}
+		}
+
+		// keep replies attached to a tombstone instead of orphaning them
+		replies, err := q.CountReplies(r.Context(), uuid.NullUUID{UUID: chirpID, Valid: true})
+		if err != nil {
+			return err
+		}
+
+		if replies > 0 {
+			err = q.TombstoneChirp(r.Context(), chirpID)
+		} else {
+			err = q.DeleteChirpByID(r.Context(), chirpID)
+		}
+		if err != nil {
+			return err
+		}
+
+		return emitEvent(r.Context(), q, eventChirpDeleted, userID, chirpDeletedEvent{
+			ID:     chirpID,
+			UserID: userID,
+		})
+	})
 	if err != nil {
-		respondWithError(w, http.StatusUnauthorized, "invalid token", err)
+		respondWithError(w, http.StatusInternalServerError, "error while deleting chirp", err)
 		return
 	}
 
+	respondWithJSON(w, http.StatusNoContent, nil)
+
+}
+
+type ChirpRevision struct {
+	ID        uuid.UUID `json:"id"`
+	ChirpID   uuid.UUID `json:"chirp_id"`
+	Body      string    `json:"body"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func (c *apiConfig) UpdateChirp(w http.ResponseWriter, r *http.Request) {
+	userID := currentUserID(r)
+
 	chirpID, err := uuid.Parse(r.PathValue("chirpID"))
 
 	if err != nil {
-		respondWithError(w, http.StatusForbidden, "could not parse chirp ID", err)
+		respondWithError(w, http.StatusBadRequest, "could not parse chirp ID", err)
 		return
 	}
 
@@ -202,16 +436,130 @@ func (c *apiConfig) DeleteChirp(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if chirp.UserID != userID {
-		respondWithError(w, http.StatusForbidden, "you cannot delete another user's chirp", nil)
+		respondWithError(w, http.StatusForbidden, "you cannot edit another user's chirp", nil)
 		return
 	}
 
-	err = c.db.DeleteChirpByID(r.Context(), chirpID)
+	_, limits, err := c.entitlementsFor(r.Context(), userID)
 	if err != nil {
-		respondWithError(w, http.StatusInternalServerError, "error while deleting chirp", err)
+		respondWithError(w, http.StatusNotFound, "error while fetching user", err)
 		return
 	}
 
-	respondWithJSON(w, http.StatusNoContent, nil)
+	if !limits.EditChirps {
+		respondWithError(w, http.StatusForbidden, "editing chirps is a Chirpy Red feature", nil)
+		return
+	}
+
+	type requestParams struct {
+		Body string `json:"body"`
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	var req requestParams
+	err = decoder.Decode(&req)
+
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "invalid request body", err)
+		return
+	}
+
+	moderated, err := c.validateChirp(req.Body, limits.MaxChirpLength)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Chirp is too long", err)
+		return
+	}
+
+	if moderated.Rejected {
+		respondWithRejection(w, moderated)
+		return
+	}
+
+	// lock the row so concurrent edits each record the body they replaced
+	var updated database.Chirp
+	err = c.withTx(r.Context(), func(q *database.Queries) error {
+		current, err := q.GetChirpByIdForUpdate(r.Context(), chirpID)
+		if err != nil {
+			return err
+		}
+
+		if current.Body == moderated.Body {
+			updated = current
+			return nil
+		}
+
+		_, err = q.CreateChirpRevision(r.Context(), database.CreateChirpRevisionParams{
+			ChirpID: chirpID,
+			Body:    current.Body,
+		})
+		if err != nil {
+			return err
+		}
+
+		updated, err = q.UpdateChirpBody(r.Context(), database.UpdateChirpBodyParams{
+			Body: moderated.Body,
+			ID:   chirpID,
+		})
+		if err != nil {
+			return err
+		}
+
+		if err := flagChirp(r.Context(), q, chirpID, moderated); err != nil {
+			return err
+		}
+
+		return indexChirpTags(r.Context(), q, updated)
+	})
+
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, http.StatusNotFound, "error while fetching chirp", err)
+		return
+	}
+
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "error while updating chirp", err)
+		return
+	}
+
+	resp := chirpFromDB(updated)
+	err = c.hydrateChirps(r.Context(), uuid.NullUUID{UUID: userID, Valid: true}, &resp)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "error while fetching chirp details", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, resp)
+}
+
+func (c *apiConfig) GetChirpRevisions(w http.ResponseWriter, r *http.Request) {
+	chirpID, err := uuid.Parse(r.PathValue("chirpID"))
+
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "could not parse chirp ID", err)
+		return
+	}
+
+	_, err = c.db.GetChirpById(r.Context(), chirpID)
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "error while fetching chirp", err)
+		return
+	}
+
+	res, err := c.db.GetChirpRevisions(r.Context(), chirpID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "error while fetching revisions", err)
+		return
+	}
+
+	revisions := []ChirpRevision{}
+	for _, row := range res {
+		revisions = append(revisions, ChirpRevision{
+			ID:        row.ID,
+			ChirpID:   row.ChirpID,
+			Body:      row.Body,
+			CreatedAt: row.CreatedAt,
+		})
+	}
 
+	respondWithJSON(w, http.StatusOK, revisions)
 }
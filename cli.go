@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+)
+
+const usage = `usage: chirpy [command]
+
+With no command, chirpy serves HTTP on :8080.
+
+commands:
+  bootstrap-admin -email EMAIL   make an existing user the first admin
+`
+
+// runCommand handles the maintenance subcommands and returns the process
+// exit code.
+func (c *apiConfig) runCommand(args []string) int {
+	switch args[0] {
+	case "bootstrap-admin":
+		return c.runBootstrapAdmin(args[1:])
+	case "help", "-h", "-help", "--help":
+		fmt.Print(usage)
+		return 0
+	}
+
+	fmt.Fprintf(os.Stderr, "unknown command %q\n\n%s", args[0], usage)
+	return 2
+}
+
+func (c *apiConfig) runBootstrapAdmin(args []string) int {
+	fs := flag.NewFlagSet("bootstrap-admin", flag.ContinueOnError)
+	email := fs.String("email", "", "email of the user to promote")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	if *email == "" {
+		fmt.Fprintln(os.Stderr, "bootstrap-admin: -email is required")
+		return 2
+	}
+
+	c.initDB()
+
+	user, err := c.bootstrapAdmin(context.Background(), *email)
+	if errors.Is(err, errAdminExists) {
+		fmt.Fprintln(os.Stderr, "bootstrap-admin: an admin already exists; use PUT /admin/users/{userID}/role instead")
+		return 1
+	}
+	if errors.Is(err, sql.ErrNoRows) {
+		fmt.Fprintf(os.Stderr, "bootstrap-admin: no user with email %s, create the account first\n", *email)
+		return 1
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "bootstrap-admin: %v\n", err)
+		return 1
+	}
+
+	fmt.Printf("%s (%s) is now an admin\n", user.Email, user.ID)
+	return 0
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"database/sql"
 	"log"
 	"os"
@@ -21,6 +22,23 @@ func InitDB(c *apiConfig) {
 
 	dbQueries := database.New(db)
 
+	c.conn = db
 	c.db = dbQueries
 
 }
+
+// withTx runs fn against a transaction-scoped copy of the queries and
+// commits only if fn returns nil.
+func (c *apiConfig) withTx(ctx context.Context, fn func(q *database.Queries) error) error {
+	tx, err := c.conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := fn(c.db.WithTx(tx)); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
@@ -0,0 +1,190 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/adi290491/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+const (
+	testEmail = "walt@example.com"
+	testIP    = "192.0.2.1"
+)
+
+// fakeQuery answers one sqlc query. It returns the rows to scan, each with
+// the columns in the order the generated code scans them; for an :exec query
+// the number of rows is the number affected.
+type fakeQuery func(args []driver.Value) ([][]driver.Value, error)
+
+// fakeDB stands in for Postgres in handler tests. Queries are looked up by
+// the name sqlc puts at the top of each one, and a query the test did not
+// provide fails, so a test states everything the handler touches.
+// Transactions are not isolated and do not roll back.
+type fakeDB struct {
+	mu      sync.Mutex
+	queries map[string]fakeQuery
+}
+
+var queryName = regexp.MustCompile(`^-- name: (\w+)`)
+
+// newTestConfig returns a config backed by queries, which run one at a time.
+func newTestConfig(t *testing.T, queries map[string]fakeQuery) *apiConfig {
+	t.Helper()
+
+	conn := sql.OpenDB(&fakeDB{queries: queries})
+	t.Cleanup(func() { conn.Close() })
+
+	return &apiConfig{
+		conn: conn,
+		db:   database.New(conn),
+	}
+}
+
+// modelRow lays out a sqlc model as the row its queries return: one column
+// per field, in field order, which is the order the generated code scans
+// SELECT * and RETURNING * into. Fakes built from models keep up when a
+// table gains a column.
+func modelRow(model any) []driver.Value {
+	v := reflect.ValueOf(model)
+	row := make([]driver.Value, v.NumField())
+	for i := range row {
+		value, err := driver.DefaultParameterConverter.ConvertValue(v.Field(i).Interface())
+		if err != nil {
+			panic(fmt.Sprintf("fakedb: %s.%s: %v", v.Type().Name(), v.Type().Field(i).Name, err))
+		}
+		row[i] = value
+	}
+	return row
+}
+
+// modelRows is modelRow for each model, as a query's result.
+func modelRows[T any](models ...T) [][]driver.Value {
+	rows := make([][]driver.Value, 0, len(models))
+	for _, model := range models {
+		rows = append(rows, modelRow(model))
+	}
+	return rows
+}
+
+// testUser is a verified account with testEmail, the handle walt and no
+// password.
+func testUser() database.User {
+	now := time.Now().UTC()
+	return database.User{
+		ID:              uuid.New(),
+		CreatedAt:       now,
+		UpdatedAt:       now,
+		Email:           testEmail,
+		Handle:          "walt",
+		Role:            "user",
+		EmailVerifiedAt: sql.NullTime{Time: now, Valid: true},
+	}
+}
+
+// serve runs h on a request from testIP and records the response.
+func serve(h http.HandlerFunc, method, target, body string, header http.Header) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	req.RemoteAddr = testIP + ":4000"
+	for key, values := range header {
+		req.Header[key] = values
+	}
+
+	w := httptest.NewRecorder()
+	h(w, req)
+	return w
+}
+
+func (db *fakeDB) run(query string, args []driver.Value) ([][]driver.Value, error) {
+	m := queryName.FindStringSubmatch(query)
+	if m == nil {
+		return nil, fmt.Errorf("fakedb: query without a name: %q", query)
+	}
+
+	q, ok := db.queries[m[1]]
+	if !ok {
+		return nil, fmt.Errorf("fakedb: unexpected query %s", m[1])
+	}
+
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	return q(args)
+}
+
+func (db *fakeDB) Connect(context.Context) (driver.Conn, error) { return fakeConn{db}, nil }
+func (db *fakeDB) Driver() driver.Driver                        { return nil }
+
+type fakeConn struct{ db *fakeDB }
+
+func (c fakeConn) Prepare(query string) (driver.Stmt, error) { return fakeStmt{c.db, query}, nil }
+func (c fakeConn) Close() error                              { return nil }
+func (c fakeConn) Begin() (driver.Tx, error)                 { return fakeTx{}, nil }
+
+type fakeTx struct{}
+
+func (fakeTx) Commit() error   { return nil }
+func (fakeTx) Rollback() error { return nil }
+
+type fakeStmt struct {
+	db    *fakeDB
+	query string
+}
+
+func (s fakeStmt) Close() error  { return nil }
+func (s fakeStmt) NumInput() int { return -1 }
+
+func (s fakeStmt) Exec(args []driver.Value) (driver.Result, error) {
+	rows, err := s.db.run(s.query, args)
+	if err != nil {
+		return nil, err
+	}
+	return driver.RowsAffected(len(rows)), nil
+}
+
+func (s fakeStmt) Query(args []driver.Value) (driver.Rows, error) {
+	rows, err := s.db.run(s.query, args)
+	if err != nil {
+		return nil, err
+	}
+	return &fakeRows{rows: rows}, nil
+}
+
+type fakeRows struct {
+	rows [][]driver.Value
+}
+
+func (r *fakeRows) Columns() []string {
+	if len(r.rows) == 0 {
+		return nil
+	}
+
+	columns := make([]string, len(r.rows[0]))
+	for i := range columns {
+		columns[i] = fmt.Sprintf("column%d", i)
+	}
+	return columns
+}
+
+func (r *fakeRows) Close() error { return nil }
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if len(r.rows) == 0 {
+		return io.EOF
+	}
+
+	copy(dest, r.rows[0])
+	r.rows = r.rows[1:]
+	return nil
+}
@@ -0,0 +1,421 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+
+	"github.com/adi290491/chirpy/internal/auth"
+	"github.com/adi290491/chirpy/internal/database"
+	"github.com/adi290491/chirpy/internal/jobs"
+	"github.com/adi290491/chirpy/internal/mail"
+	"github.com/google/uuid"
+)
+
+const (
+	defaultMailDir       = "mail"
+	defaultMailFrom      = "Chirpy <no-reply@localhost>"
+	defaultPublicURL     = "http://localhost:8080"
+	passwordResetExpiry  = time.Hour
+	verifyEmailExpiry    = 48 * time.Hour
+	passwordResetSubject = "Reset your Chirpy password"
+	verifyEmailSubject   = "Confirm your Chirpy email address"
+)
+
+var (
+	errInvalidEmailToken = errors.New("token is invalid, used or expired")
+	errEmailChanged      = errors.New("email address changed after the token was sent")
+)
+
+func (c *apiConfig) initMailer() {
+	from := os.Getenv("MAIL_FROM")
+	if from == "" {
+		from = defaultMailFrom
+	}
+
+	c.publicURL = os.Getenv("PUBLIC_URL")
+	if c.publicURL == "" {
+		c.publicURL = defaultPublicURL
+	}
+
+	c.requireVerifiedEmail = os.Getenv("REQUIRE_VERIFIED_EMAIL") == "true"
+
+	switch kind := os.Getenv("MAILER"); kind {
+	case "smtp":
+		port := os.Getenv("SMTP_PORT")
+		if port == "" {
+			port = "587"
+		}
+		c.mailer = mail.SMTP{
+			Host:     os.Getenv("SMTP_HOST"),
+			Port:     port,
+			Username: os.Getenv("SMTP_USERNAME"),
+			Password: os.Getenv("SMTP_PASSWORD"),
+			From:     from,
+		}
+	case "file":
+		dir := os.Getenv("MAIL_DIR")
+		if dir == "" {
+			dir = defaultMailDir
+		}
+		mailer, err := mail.NewFile(dir, from)
+		if err != nil {
+			log.Fatalf("could not initialise mail directory: %v", err)
+		}
+		c.mailer = mailer
+	case "", "log":
+		if c.PLATFORM != "dev" {
+			log.Printf("MAILER is not set; emails will only be logged")
+		}
+		c.mailer = mail.Log{}
+	default:
+		log.Fatalf("unknown MAILER %q", kind)
+	}
+}
+
+// emailTokenJob is the payload of a jobSendEmailToken job. The signed token
+// is only made when the email is sent, so it is never stored.
+type emailTokenJob struct {
+	UserID    uuid.UUID `json:"user_id"`
+	TokenID   uuid.UUID `json:"token_id"`
+	Purpose   string    `json:"purpose"`
+	Email     string    `json:"email"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// queueEmailToken records a single-use token for user and queues the email
+// that carries it, both in q's transaction. Earlier unused tokens for the
+// same purpose stop working.
+func queueEmailToken(ctx context.Context, q *database.Queries, user database.User, purpose string, expiresIn time.Duration) error {
+	err := q.InvalidateEmailTokens(ctx, database.InvalidateEmailTokensParams{
+		UserID:  user.ID,
+		Purpose: purpose,
+	})
+	if err != nil {
+		return err
+	}
+
+	row, err := q.CreateEmailToken(ctx, database.CreateEmailTokenParams{
+		UserID:    user.ID,
+		Purpose:   purpose,
+		Email:     user.Email,
+		ExpiresAt: time.Now().UTC().Add(expiresIn),
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = jobs.Enqueue(ctx, q, jobSendEmailToken, emailTokenJob{
+		UserID:    user.ID,
+		TokenID:   row.ID,
+		Purpose:   purpose,
+		Email:     user.Email,
+		ExpiresAt: row.ExpiresAt,
+	})
+	return err
+}
+
+// useEmailToken verifies a signed token and burns its record.
+func useEmailToken(ctx context.Context, q *database.Queries, keys *auth.Keyring, token, purpose string) (database.EmailToken, error) {
+	userID, tokenID, err := auth.ParseActionToken(token, purpose, keys)
+	if err != nil {
+		return database.EmailToken{}, errInvalidEmailToken
+	}
+
+	row, err := q.UseEmailToken(ctx, database.UseEmailTokenParams{
+		ID:      tokenID,
+		Purpose: purpose,
+	})
+	if errors.Is(err, sql.ErrNoRows) || (err == nil && row.UserID != userID) {
+		return database.EmailToken{}, errInvalidEmailToken
+	}
+
+	return row, err
+}
+
+func (c *apiConfig) actionURL(path, token string) string {
+	return c.publicURL + path + "?token=" + url.QueryEscape(token)
+}
+
+func queueVerificationEmail(ctx context.Context, q *database.Queries, user database.User) error {
+	return queueEmailToken(ctx, q, user, auth.PurposeVerifyEmail, verifyEmailExpiry)
+}
+
+// sendEmailToken handles jobSendEmailToken.
+func (c *apiConfig) sendEmailToken(ctx context.Context, payload json.RawMessage) error {
+	var job emailTokenJob
+	if err := json.Unmarshal(payload, &job); err != nil {
+		return jobs.Permanent(err)
+	}
+
+	expiresIn := time.Until(job.ExpiresAt)
+	if expiresIn <= 0 {
+		return jobs.Permanent(errors.New("token expired before its email could be sent"))
+	}
+
+	token, err := auth.MakeActionToken(job.UserID, job.TokenID, job.Purpose, c.keyring, expiresIn)
+	if err != nil {
+		return err
+	}
+
+	msg := mail.Message{To: job.Email}
+	switch job.Purpose {
+	case auth.PurposeVerifyEmail:
+		msg.Subject = verifyEmailSubject
+		msg.Body = fmt.Sprintf("Confirm that this is your address by opening the link below:\n\n%s\n\nThe link expires in %s.\n",
+			c.actionURL("/app/verify-email", token), verifyEmailExpiry)
+	case auth.PurposePasswordReset:
+		msg.Subject = passwordResetSubject
+		msg.Body = fmt.Sprintf("Someone asked to reset your Chirpy password. If it was you, open the link below:\n\n%s\n\nThe link expires in %s. If you did not ask for this, ignore this email.\n",
+			c.actionURL("/app/reset-password", token), passwordResetExpiry)
+	default:
+		return jobs.Permanent(fmt.Errorf("unknown token purpose %q", job.Purpose))
+	}
+
+	return c.mailer.Send(ctx, msg)
+}
+
+type passwordResetJob struct {
+	Email string `json:"email"`
+}
+
+// startPasswordReset handles jobRequestPasswordReset. Looking the address up
+// here rather than in the handler keeps whether it has an account, and the
+// work done when it does, out of the response.
+func (c *apiConfig) startPasswordReset(ctx context.Context, payload json.RawMessage) error {
+	var job passwordResetJob
+	if err := json.Unmarshal(payload, &job); err != nil {
+		return jobs.Permanent(err)
+	}
+
+	user, err := c.db.GetUserByEmail(ctx, job.Email)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	return c.withTx(ctx, func(q *database.Queries) error {
+		return queueEmailToken(ctx, q, user, auth.PurposePasswordReset, passwordResetExpiry)
+	})
+}
+
+func (c *apiConfig) RequestPasswordReset(w http.ResponseWriter, r *http.Request) {
+	type requestParams struct {
+		Email string `json:"email"`
+	}
+
+	var req requestParams
+	decoder := json.NewDecoder(r.Body)
+	err := decoder.Decode(&req)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "invalid request body", err)
+		return
+	}
+
+	keys := passwordResetThrottleKeys(req.Email, clientFromRequest(r).IP)
+	_, wait, err := c.claimLoginAttempt(r.Context(), keys)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "error while requesting password reset", err)
+		return
+	}
+
+	if wait > 0 {
+		respondThrottled(w, wait, "too many password reset requests, try again later")
+		return
+	}
+
+	_, err = jobs.Enqueue(r.Context(), c.db, jobRequestPasswordReset, passwordResetJob{Email: req.Email})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "error while requesting password reset", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusAccepted, nil)
+}
+
+func (c *apiConfig) ConfirmPasswordReset(w http.ResponseWriter, r *http.Request) {
+	type requestParams struct {
+		Token    string `json:"token"`
+		Password string `json:"password"`
+	}
+
+	var req requestParams
+	decoder := json.NewDecoder(r.Body)
+	err := decoder.Decode(&req)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "invalid request body", err)
+		return
+	}
+
+	if req.Password == "" {
+		respondWithError(w, http.StatusBadRequest, "password is required", nil)
+		return
+	}
+
+	hash, err := auth.HashPassword(req.Password)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Password hash error", err)
+		return
+	}
+
+	var user database.User
+	err = c.withTx(r.Context(), func(q *database.Queries) error {
+		token, err := useEmailToken(r.Context(), q, c.keyring, req.Token, auth.PurposePasswordReset)
+		if err != nil {
+			return err
+		}
+
+		user, err = q.GetUserByID(r.Context(), token.UserID)
+		if err != nil {
+			return err
+		}
+
+		if user.Email != token.Email {
+			return errEmailChanged
+		}
+
+		err = q.UpdateUserPassword(r.Context(), database.UpdateUserPasswordParams{
+			HashedPassword: hash,
+			ID:             user.ID,
+		})
+		if err != nil {
+			return err
+		}
+
+		// the link reached the inbox, which is all verification proves
+		_, err = q.MarkEmailVerified(r.Context(), database.MarkEmailVerifiedParams{
+			ID:    user.ID,
+			Email: user.Email,
+		})
+		if err != nil {
+			return err
+		}
+
+		// a locked-out owner can get back in with the new password, and ask
+		// for another reset straight away
+		err = q.ClearLoginThrottle(r.Context(), accountThrottleKey(user.Email))
+		if err != nil {
+			return err
+		}
+
+		err = q.ClearLoginThrottle(r.Context(), passwordResetThrottleKey(user.Email))
+		if err != nil {
+			return err
+		}
+
+		// whoever knew the old password is signed out everywhere, and any
+		// API token they may have minted stops working
+		err = q.RevokeAllAPITokens(r.Context(), user.ID)
+		if err != nil {
+			return err
+		}
+
+		return q.RevokeAllSessions(r.Context(), user.ID)
+	})
+
+	if errors.Is(err, errInvalidEmailToken) || errors.Is(err, errEmailChanged) {
+		respondWithError(w, http.StatusBadRequest, "reset link is invalid or has expired", err)
+		return
+	}
+
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "error while resetting password", err)
+		return
+	}
+
+	c.audit(r.Context(), r, auditPasswordReset, uuid.NullUUID{UUID: user.ID, Valid: true}, user.Email)
+
+	respondWithJSON(w, http.StatusNoContent, nil)
+}
+
+func (c *apiConfig) VerifyEmail(w http.ResponseWriter, r *http.Request) {
+	type requestParams struct {
+		Token string `json:"token"`
+	}
+
+	var req requestParams
+	decoder := json.NewDecoder(r.Body)
+	err := decoder.Decode(&req)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "invalid request body", err)
+		return
+	}
+
+	err = c.withTx(r.Context(), func(q *database.Queries) error {
+		token, err := useEmailToken(r.Context(), q, c.keyring, req.Token, auth.PurposeVerifyEmail)
+		if err != nil {
+			return err
+		}
+
+		verified, err := q.MarkEmailVerified(r.Context(), database.MarkEmailVerifiedParams{
+			ID:    token.UserID,
+			Email: token.Email,
+		})
+		if err != nil {
+			return err
+		}
+		if verified == 0 {
+			return errEmailChanged
+		}
+
+		return nil
+	})
+
+	if errors.Is(err, errInvalidEmailToken) || errors.Is(err, errEmailChanged) {
+		respondWithError(w, http.StatusBadRequest, "verification link is invalid or has expired", err)
+		return
+	}
+
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "error while verifying email", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusNoContent, nil)
+}
+
+func (c *apiConfig) ResendVerificationEmail(w http.ResponseWriter, r *http.Request) {
+	userID := currentUserID(r)
+
+	user, err := c.db.GetUserByID(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "error while fetching user", err)
+		return
+	}
+
+	if user.EmailVerifiedAt.Valid {
+		respondWithError(w, http.StatusConflict, "email address is already verified", nil)
+		return
+	}
+
+	keys := verificationThrottleKeys(user.ID, user.Email, clientFromRequest(r).IP)
+	_, wait, err := c.claimLoginAttempt(r.Context(), keys)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "error while sending verification email", err)
+		return
+	}
+
+	if wait > 0 {
+		respondThrottled(w, wait, "too many verification emails, try again later")
+		return
+	}
+
+	err = c.withTx(r.Context(), func(q *database.Queries) error {
+		return queueVerificationEmail(r.Context(), q, user)
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "error while sending verification email", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusAccepted, nil)
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/adi290491/chirpy/internal/auth"
+	"github.com/adi290491/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+func requestPasswordReset(c *apiConfig, email string) *httptest.ResponseRecorder {
+	return serve(c.RequestPasswordReset, http.MethodPost, "/api/password-reset", `{"email":"`+email+`"}`, nil)
+}
+
+func TestRequestPasswordResetIsThrottled(t *testing.T) {
+	store := newLoginStore(t)
+	queries := store.queries()
+
+	queued := 0
+	queries["EnqueueJob"] = func([]driver.Value) ([][]driver.Value, error) {
+		queued++
+		return [][]driver.Value{{uuid.NewString()}}, nil
+	}
+	c := newTestConfig(t, queries)
+
+	if w := requestPasswordReset(c, testEmail); w.Code != http.StatusAccepted {
+		t.Fatalf("expected 202, got %d", w.Code)
+	}
+
+	w := requestPasswordReset(c, strings.ToUpper(testEmail))
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected a second request for the same inbox to get 429, got %d", w.Code)
+	}
+	if got := w.Header().Get("Retry-After"); got != "60" {
+		t.Errorf("expected Retry-After 60, got %q", got)
+	}
+	if queued != 1 {
+		t.Errorf("expected one reset email to be queued, got %d", queued)
+	}
+
+	// the address may still ask for a few other inboxes
+	if w := requestPasswordReset(c, "other@example.com"); w.Code != http.StatusAccepted {
+		t.Fatalf("expected another inbox to get 202, got %d", w.Code)
+	}
+
+	if _, ok := store.throttles[accountThrottleKey(testEmail)]; ok {
+		t.Error("expected reset requests to leave the login counters alone")
+	}
+}
+
+func TestResendVerificationEmailIsThrottled(t *testing.T) {
+	store := newLoginStore(t)
+	store.user.EmailVerifiedAt = sql.NullTime{}
+	queries := store.queries()
+
+	queries["GetUserByID"] = func([]driver.Value) ([][]driver.Value, error) {
+		return modelRows(store.user), nil
+	}
+	queries["InvalidateEmailTokens"] = func([]driver.Value) ([][]driver.Value, error) {
+		return nil, nil
+	}
+	queries["CreateEmailToken"] = func(args []driver.Value) ([][]driver.Value, error) {
+		return modelRows(database.EmailToken{
+			ID:        uuid.New(),
+			UserID:    store.user.ID,
+			Purpose:   args[1].(string),
+			Email:     args[2].(string),
+			CreatedAt: time.Now().UTC(),
+			ExpiresAt: args[3].(time.Time),
+		}), nil
+	}
+	queued := 0
+	queries["EnqueueJob"] = func([]driver.Value) ([][]driver.Value, error) {
+		queued++
+		return [][]driver.Value{{uuid.NewString()}}, nil
+	}
+	c := newTestConfig(t, queries)
+
+	resend := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/verify-email/send", nil)
+		req.RemoteAddr = testIP + ":4000"
+		req = req.WithContext(auth.ContextWithClaims(req.Context(), &auth.Claims{UserID: store.user.ID}))
+
+		w := httptest.NewRecorder()
+		c.ResendVerificationEmail(w, req)
+		return w
+	}
+
+	if w := resend(); w.Code != http.StatusAccepted {
+		t.Fatalf("expected 202, got %d: %s", w.Code, w.Body)
+	}
+
+	w := resend()
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected a second email within a minute to get 429, got %d", w.Code)
+	}
+	if got := w.Header().Get("Retry-After"); got != "60" {
+		t.Errorf("expected Retry-After 60, got %q", got)
+	}
+	if queued != 1 {
+		t.Errorf("expected one verification email to be queued, got %d", queued)
+	}
+
+	if _, ok := store.throttles["verify:"+testEmail]; !ok {
+		t.Error("expected the inbox to be counted")
+	}
+}
@@ -0,0 +1,31 @@
+package main
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/adi290491/chirpy/internal/database"
+	"github.com/adi290491/chirpy/internal/entitlements"
+	"github.com/google/uuid"
+)
+
+// entitlementsFor loads the user and what their tier allows. It reads the
+// database rather than the token, so a new subscription applies at once.
+func (c *apiConfig) entitlementsFor(ctx context.Context, userID uuid.UUID) (database.User, entitlements.Entitlements, error) {
+	user, err := c.db.GetUserByID(ctx, userID)
+	if err != nil {
+		return database.User{}, entitlements.Entitlements{}, err
+	}
+
+	return user, entitlements.For(entitlements.TierFor(user.IsChirpyRed)), nil
+}
+
+func (c *apiConfig) GetEntitlements(w http.ResponseWriter, r *http.Request) {
+	_, limits, err := c.entitlementsFor(r.Context(), currentUserID(r))
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "error while fetching user", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, limits)
+}
@@ -0,0 +1,207 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/adi290491/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+type FollowEntry struct {
+	UserID     uuid.UUID `json:"user_id"`
+	FollowedAt time.Time `json:"followed_at"`
+}
+
+type FollowPage struct {
+	Users      []FollowEntry `json:"users"`
+	NextCursor string        `json:"next_cursor,omitempty"`
+}
+
+type userFollowedEvent struct {
+	UserID     uuid.UUID `json:"user_id"`
+	FollowerID uuid.UUID `json:"follower_id"`
+}
+
+func (c *apiConfig) FollowUser(w http.ResponseWriter, r *http.Request) {
+	followerID := currentUserID(r)
+
+	followeeID, err := uuid.Parse(r.PathValue("userID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "could not parse user ID", err)
+		return
+	}
+
+	if followeeID == followerID {
+		respondWithError(w, http.StatusBadRequest, "you cannot follow yourself", errors.New("self follow"))
+		return
+	}
+
+	_, err = c.db.GetUserByID(r.Context(), followeeID)
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "error while fetching user", err)
+		return
+	}
+
+	err = c.withTx(r.Context(), func(q *database.Queries) error {
+		followed, err := q.FollowUser(r.Context(), database.FollowUserParams{
+			FollowerID: followerID,
+			FolloweeID: followeeID,
+		})
+		if err != nil || followed == 0 {
+			return err
+		}
+
+		return emitEvent(r.Context(), q, eventUserFollowed, followeeID, userFollowedEvent{
+			UserID:     followeeID,
+			FollowerID: followerID,
+		})
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "error while following user", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusNoContent, nil)
+}
+
+func (c *apiConfig) UnfollowUser(w http.ResponseWriter, r *http.Request) {
+	followerID := currentUserID(r)
+
+	followeeID, err := uuid.Parse(r.PathValue("userID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "could not parse user ID", err)
+		return
+	}
+
+	err = c.db.UnfollowUser(r.Context(), database.UnfollowUserParams{
+		FollowerID: followerID,
+		FolloweeID: followeeID,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "error while unfollowing user", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusNoContent, nil)
+}
+
+func (c *apiConfig) GetFollowers(w http.ResponseWriter, r *http.Request) {
+	userID, limit, cursorCreatedAt, cursorID, err := parseFollowListRequest(r)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "invalid request", err)
+		return
+	}
+
+	res, err := c.db.ListFollowers(r.Context(), database.ListFollowersParams{
+		UserID:          userID,
+		CursorCreatedAt: cursorCreatedAt,
+		CursorID:        cursorID,
+		Limit:           limit + 1,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "error while fetching followers", err)
+		return
+	}
+
+	page := FollowPage{Users: []FollowEntry{}}
+	if len(res) > int(limit) {
+		res = res[:limit]
+		last := res[len(res)-1]
+		page.NextCursor = encodeCursor(last.CreatedAt, last.UserID)
+	}
+
+	for _, row := range res {
+		page.Users = append(page.Users, FollowEntry{
+			UserID:     row.UserID,
+			FollowedAt: row.CreatedAt,
+		})
+	}
+
+	respondWithJSON(w, http.StatusOK, page)
+}
+
+func (c *apiConfig) GetFollowing(w http.ResponseWriter, r *http.Request) {
+	userID, limit, cursorCreatedAt, cursorID, err := parseFollowListRequest(r)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "invalid request", err)
+		return
+	}
+
+	res, err := c.db.ListFollowing(r.Context(), database.ListFollowingParams{
+		UserID:          userID,
+		CursorCreatedAt: cursorCreatedAt,
+		CursorID:        cursorID,
+		Limit:           limit + 1,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "error while fetching followed users", err)
+		return
+	}
+
+	page := FollowPage{Users: []FollowEntry{}}
+	if len(res) > int(limit) {
+		res = res[:limit]
+		last := res[len(res)-1]
+		page.NextCursor = encodeCursor(last.CreatedAt, last.UserID)
+	}
+
+	for _, row := range res {
+		page.Users = append(page.Users, FollowEntry{
+			UserID:     row.UserID,
+			FollowedAt: row.CreatedAt,
+		})
+	}
+
+	respondWithJSON(w, http.StatusOK, page)
+}
+
+func (c *apiConfig) GetTimeline(w http.ResponseWriter, r *http.Request) {
+	userID := currentUserID(r)
+
+	limit, err := parseLimit(r.URL.Query().Get("limit"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "invalid limit", err)
+		return
+	}
+
+	cursorCreatedAt, cursorID, err := parseCursor(r.URL.Query().Get("cursor"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "invalid cursor", err)
+		return
+	}
+
+	res, err := c.db.GetTimeline(r.Context(), database.GetTimelineParams{
+		FollowerID:      userID,
+		CursorCreatedAt: cursorCreatedAt,
+		CursorID:        cursorID,
+		Limit:           limit + 1,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "error while fetching timeline", err)
+		return
+	}
+
+	c.respondWithChirpPage(w, r, res, limit)
+}
+
+func parseFollowListRequest(r *http.Request) (uuid.UUID, int32, sql.NullTime, uuid.NullUUID, error) {
+	userID, err := uuid.Parse(r.PathValue("userID"))
+	if err != nil {
+		return uuid.Nil, 0, sql.NullTime{}, uuid.NullUUID{}, err
+	}
+
+	limit, err := parseLimit(r.URL.Query().Get("limit"))
+	if err != nil {
+		return uuid.Nil, 0, sql.NullTime{}, uuid.NullUUID{}, err
+	}
+
+	cursorCreatedAt, cursorID, err := parseCursor(r.URL.Query().Get("cursor"))
+	if err != nil {
+		return uuid.Nil, 0, sql.NullTime{}, uuid.NullUUID{}, err
+	}
+
+	return userID, limit, cursorCreatedAt, cursorID, nil
+}
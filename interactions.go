@@ -0,0 +1,107 @@
+package main
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/adi290491/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+// attachChirpStats fills in like/rechirp counts for every chirp with a single
+// query, and liked_by_me when viewer is set.
+func (c *apiConfig) attachChirpStats(ctx context.Context, viewer uuid.NullUUID, chirps ...*Chirp) error {
+	if len(chirps) == 0 {
+		return nil
+	}
+
+	ids := make([]uuid.UUID, 0, len(chirps))
+	for _, chirp := range chirps {
+		ids = append(ids, chirp.ID)
+	}
+
+	stats, err := c.db.GetChirpStats(ctx, database.GetChirpStatsParams{
+		ViewerID: viewer,
+		ChirpIds: ids,
+	})
+	if err != nil {
+		return err
+	}
+
+	byID := make(map[uuid.UUID]database.GetChirpStatsRow, len(stats))
+	for _, row := range stats {
+		byID[row.ChirpID] = row
+	}
+
+	for _, chirp := range chirps {
+		row := byID[chirp.ID]
+		chirp.LikeCount = row.LikeCount
+		chirp.RechirpCount = row.RechirpCount
+		if viewer.Valid {
+			likedByMe := row.LikedByMe
+			chirp.LikedByMe = &likedByMe
+		}
+	}
+
+	return nil
+}
+
+func chirpRefs(chirps []Chirp) []*Chirp {
+	refs := make([]*Chirp, len(chirps))
+	for i := range chirps {
+		refs[i] = &chirps[i]
+	}
+	return refs
+}
+
+func (c *apiConfig) LikeChirp(w http.ResponseWriter, r *http.Request) {
+	c.setChirpInteraction(w, r, func(ctx context.Context, userID, chirpID uuid.UUID) error {
+		return c.db.LikeChirp(ctx, database.LikeChirpParams{UserID: userID, ChirpID: chirpID})
+	})
+}
+
+func (c *apiConfig) UnlikeChirp(w http.ResponseWriter, r *http.Request) {
+	c.setChirpInteraction(w, r, func(ctx context.Context, userID, chirpID uuid.UUID) error {
+		return c.db.UnlikeChirp(ctx, database.UnlikeChirpParams{UserID: userID, ChirpID: chirpID})
+	})
+}
+
+func (c *apiConfig) RechirpChirp(w http.ResponseWriter, r *http.Request) {
+	c.setChirpInteraction(w, r, func(ctx context.Context, userID, chirpID uuid.UUID) error {
+		return c.db.RechirpChirp(ctx, database.RechirpChirpParams{UserID: userID, ChirpID: chirpID})
+	})
+}
+
+func (c *apiConfig) UnrechirpChirp(w http.ResponseWriter, r *http.Request) {
+	c.setChirpInteraction(w, r, func(ctx context.Context, userID, chirpID uuid.UUID) error {
+		return c.db.UnrechirpChirp(ctx, database.UnrechirpChirpParams{UserID: userID, ChirpID: chirpID})
+	})
+}
+
+// setChirpInteraction handles the shared auth and lookup for the like and
+// rechirp endpoints. The writes are idempotent inserts/deletes on a
+// (user_id, chirp_id) key, so concurrent likes can never double count.
+func (c *apiConfig) setChirpInteraction(w http.ResponseWriter, r *http.Request, apply func(ctx context.Context, userID, chirpID uuid.UUID) error) {
+	userID := currentUserID(r)
+
+	chirpID, err := uuid.Parse(r.PathValue("chirpID"))
+
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "could not parse chirp ID", err)
+		return
+	}
+
+	_, err = c.db.GetChirpById(r.Context(), chirpID)
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "error while fetching chirp", err)
+		return
+	}
+
+	err = apply(r.Context(), userID, chirpID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "error while updating chirp", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusNoContent, nil)
+}
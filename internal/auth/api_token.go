@@ -0,0 +1,51 @@
+package auth
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// APITokenPrefix starts every personal access token, so they are easy to
+// tell apart from JWTs and to spot in leaked text.
+const APITokenPrefix = "chirpy_pat_"
+
+// Scope names one thing a personal access token may do. Access tokens from a
+// login are not limited by scopes.
+type Scope string
+
+const (
+	ScopeChirpsRead   Scope = "chirps:read"
+	ScopeChirpsWrite  Scope = "chirps:write"
+	ScopeFollowsWrite Scope = "follows:write"
+)
+
+var scopes = []Scope{ScopeChirpsRead, ScopeChirpsWrite, ScopeFollowsWrite}
+
+func ParseScope(s string) (Scope, error) {
+	scope := Scope(s)
+	if !slices.Contains(scopes, scope) {
+		return "", fmt.Errorf("unknown scope %q", s)
+	}
+	return scope, nil
+}
+
+func MakeAPIToken() (string, error) {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return "", err
+	}
+	return APITokenPrefix + base64.RawURLEncoding.EncodeToString(key), nil
+}
+
+func IsAPIToken(token string) bool {
+	return strings.HasPrefix(token, APITokenPrefix)
+}
+
+// HashAPIToken returns the form of a personal access token kept in the
+// database. Like refresh tokens they are random enough for a plain SHA-256.
+func HashAPIToken(token string) string {
+	return HashRefreshToken(token)
+}
@@ -0,0 +1,55 @@
+package auth
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestMakeAPIToken(t *testing.T) {
+	token, err := MakeAPIToken()
+	if err != nil {
+		t.Fatalf("MakeAPIToken failed: %v", err)
+	}
+
+	if !IsAPIToken(token) {
+		t.Fatalf("expected %q to be recognised as an API token", token)
+	}
+
+	other, _ := MakeAPIToken()
+	if token == other || HashAPIToken(token) == HashAPIToken(other) {
+		t.Fatalf("expected distinct tokens and hashes")
+	}
+}
+
+func TestParseScope(t *testing.T) {
+	if scope, err := ParseScope("chirps:write"); err != nil || scope != ScopeChirpsWrite {
+		t.Fatalf("expected chirps:write, got %q, %v", scope, err)
+	}
+
+	if _, err := ParseScope("users:manage_roles"); err == nil {
+		t.Fatalf("expected an error for an unknown scope")
+	}
+}
+
+func TestClaimsHasScope(t *testing.T) {
+	session := &Claims{UserID: uuid.New(), Role: RoleUser}
+	if !session.HasScope(ScopeChirpsWrite) || !session.HasScope("") {
+		t.Fatalf("an access token should not be limited by scopes")
+	}
+
+	pat := &Claims{
+		UserID:     uuid.New(),
+		APITokenID: uuid.New(),
+		Scopes:     []Scope{ScopeChirpsRead},
+	}
+	if !pat.HasScope(ScopeChirpsRead) {
+		t.Fatalf("expected the granted scope to pass")
+	}
+	if pat.HasScope(ScopeChirpsWrite) {
+		t.Fatalf("expected a scope that was not granted to fail")
+	}
+	if pat.HasScope("") {
+		t.Fatalf("an API token must not pass where no scope is accepted")
+	}
+}
@@ -4,7 +4,9 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"slices"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/alexedwards/argon2id"
@@ -33,60 +35,223 @@ func CheckPasswordHash(password, hash string) (bool, error) {
 	return match, nil
 }
 
-func MakeJWT(userID uuid.UUID, tokenSecret string, expiresIn time.Duration) (string, error) {
-	token := jwt.NewWithClaims(
-		jwt.SigningMethodHS256,
-		jwt.RegisteredClaims{
-			Issuer:    issuerType,
-			IssuedAt:  jwt.NewNumericDate(time.Now().UTC()),
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(expiresIn)),
-			Subject:   userID.String(),
-		},
-	)
+// dummyHash is compared against when there is no account, so a login for an
+// unknown email takes as long as one with a wrong password.
+var dummyHash = sync.OnceValue(func() string {
+	hash, err := HashPassword("chirpy-dummy-password")
+	if err != nil {
+		panic(err)
+	}
+	return hash
+})
 
-	return token.SignedString([]byte(tokenSecret))
+// CheckDummyPasswordHash does the work of CheckPasswordHash without a real
+// hash. The result is always a mismatch.
+func CheckDummyPasswordHash(password string) {
+	argon2id.ComparePasswordAndHash(password, dummyHash())
 }
 
-func ValidateJWT(tokenString, tokenSecret string) (uuid.UUID, error) {
-	token, err := jwt.ParseWithClaims(tokenString, &jwt.RegisteredClaims{}, func(t *jwt.Token) (interface{}, error) {
+// tokenClaims is the JWT payload: the registered claims plus the user's role.
+// Purpose is empty for access tokens and names the single job of any other
+// token, so those can never be used as access tokens.
+type tokenClaims struct {
+	Role    Role   `json:"role,omitempty"`
+	Purpose string `json:"purpose,omitempty"`
+	jwt.RegisteredClaims
+}
+
+const purposeMFA = "mfa"
+
+func signToken(keys *Keyring, userID uuid.UUID, claims tokenClaims, expiresIn time.Duration) (string, error) {
+	kid, signingKey, err := keys.signer()
+	if err != nil {
+		return "", err
+	}
+
+	claims.Issuer = issuerType
+	claims.IssuedAt = jwt.NewNumericDate(time.Now().UTC())
+	claims.ExpiresAt = jwt.NewNumericDate(time.Now().Add(expiresIn))
+	claims.Subject = userID.String()
 
-		if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fmt.Errorf("unexpected signing method: %v", t.Header["alg"])
+	token := jwt.NewWithClaims(jwt.SigningMethodEdDSA, claims)
+	token.Header["kid"] = kid
+
+	return token.SignedString(signingKey)
+}
+
+// verifyToken checks the signature against the key named by the kid header,
+// so tokens signed before a key rotation keep working, then the expiry,
+// issuer and subject.
+func verifyToken(tokenString string, keys *Keyring) (*tokenClaims, string, uuid.UUID, error) {
+	var kid string
+	token, err := jwt.ParseWithClaims(tokenString, &tokenClaims{}, func(t *jwt.Token) (interface{}, error) {
+		var ok bool
+		kid, ok = t.Header["kid"].(string)
+		if !ok {
+			return nil, errors.New("token has no kid header")
 		}
-		return []byte(tokenSecret), nil
-	})
+		return keys.verificationKey(kid)
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodEdDSA.Alg()}))
 
 	if err != nil {
-		return uuid.Nil, err
+		return nil, "", uuid.Nil, err
 	}
 
-	claims, ok := token.Claims.(*jwt.RegisteredClaims)
+	claims, ok := token.Claims.(*tokenClaims)
 
 	if !ok || !token.Valid {
-		return uuid.Nil, errors.New("invalid token")
+		return nil, "", uuid.Nil, errors.New("invalid token")
 	}
 
 	userID, err := claims.GetSubject()
 
 	if err != nil {
-		return uuid.Nil, err
+		return nil, "", uuid.Nil, err
 	}
 
 	issuer, err := claims.GetIssuer()
 	if err != nil {
-		return uuid.Nil, err
+		return nil, "", uuid.Nil, err
 	}
 
 	if issuer != issuerType {
-		return uuid.Nil, errors.New("invalid issuer")
+		return nil, "", uuid.Nil, errors.New("invalid issuer")
 	}
 
 	id, err := uuid.Parse(userID)
 	if err != nil {
-		return uuid.Nil, fmt.Errorf("invalid user ID: %v", err)
+		return nil, "", uuid.Nil, fmt.Errorf("invalid user ID: %v", err)
 	}
 
-	return id, nil
+	return claims, kid, id, nil
+}
+
+// MakeJWT signs an access token with the keyring's signing key and names
+// that key in the kid header.
+func MakeJWT(userID uuid.UUID, role Role, keys *Keyring, expiresIn time.Duration) (string, error) {
+	return signToken(keys, userID, tokenClaims{Role: role}, expiresIn)
+}
+
+// Claims is what Chirpy reads out of a verified access token or personal
+// access token. APITokenID and Scopes are only set for the latter.
+type Claims struct {
+	UserID     uuid.UUID
+	Role       Role
+	KeyID      string
+	IssuedAt   time.Time
+	ExpiresAt  time.Time
+	APITokenID uuid.UUID
+	Scopes     []Scope
+}
+
+// HasScope reports whether the caller may act within scope. Access tokens
+// carry all of the user's rights; personal access tokens only the scopes they
+// were created with, and never the empty scope, which marks endpoints that
+// need a login.
+func (c *Claims) HasScope(scope Scope) bool {
+	if c.APITokenID == uuid.Nil {
+		return true
+	}
+	return scope != "" && slices.Contains(c.Scopes, scope)
+}
+
+// ParseJWT verifies an access token and returns its claims.
+func ParseJWT(tokenString string, keys *Keyring) (*Claims, error) {
+	claims, kid, userID, err := verifyToken(tokenString, keys)
+	if err != nil {
+		return nil, err
+	}
+
+	if claims.Purpose != "" {
+		return nil, fmt.Errorf("%s token is not an access token", claims.Purpose)
+	}
+
+	role := claims.Role
+	if role == "" {
+		role = RoleUser
+	}
+	if _, err := ParseRole(string(role)); err != nil {
+		return nil, err
+	}
+
+	parsed := &Claims{
+		UserID: userID,
+		Role:   role,
+		KeyID:  kid,
+	}
+	if claims.IssuedAt != nil {
+		parsed.IssuedAt = claims.IssuedAt.Time
+	}
+	if claims.ExpiresAt != nil {
+		parsed.ExpiresAt = claims.ExpiresAt.Time
+	}
+
+	return parsed, nil
+}
+
+// MakeMFAChallenge signs the token a user receives after a correct password
+// when they still owe a second factor. It proves the password step only.
+func MakeMFAChallenge(userID uuid.UUID, keys *Keyring, expiresIn time.Duration) (string, error) {
+	return signToken(keys, userID, tokenClaims{Purpose: purposeMFA}, expiresIn)
+}
+
+// ParseMFAChallenge verifies a token from MakeMFAChallenge.
+func ParseMFAChallenge(tokenString string, keys *Keyring) (uuid.UUID, error) {
+	claims, _, userID, err := verifyToken(tokenString, keys)
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	if claims.Purpose != purposeMFA {
+		return uuid.Nil, errors.New("not an MFA challenge token")
+	}
+
+	return userID, nil
+}
+
+// Purposes of the single-use tokens sent by email.
+const (
+	PurposePasswordReset = "password_reset"
+	PurposeVerifyEmail   = "verify_email"
+)
+
+// MakeActionToken signs a token that authorises one action, such as a
+// password reset. tokenID becomes the jti claim; the caller records it so
+// the token can be redeemed only once.
+func MakeActionToken(userID, tokenID uuid.UUID, purpose string, keys *Keyring, expiresIn time.Duration) (string, error) {
+	claims := tokenClaims{Purpose: purpose}
+	claims.ID = tokenID.String()
+	return signToken(keys, userID, claims, expiresIn)
+}
+
+// ParseActionToken verifies a token from MakeActionToken and checks it was
+// made for purpose. It returns the user and token IDs.
+func ParseActionToken(tokenString, purpose string, keys *Keyring) (uuid.UUID, uuid.UUID, error) {
+	claims, _, userID, err := verifyToken(tokenString, keys)
+	if err != nil {
+		return uuid.Nil, uuid.Nil, err
+	}
+
+	if claims.Purpose != purpose {
+		return uuid.Nil, uuid.Nil, fmt.Errorf("not a %s token", purpose)
+	}
+
+	tokenID, err := uuid.Parse(claims.ID)
+	if err != nil {
+		return uuid.Nil, uuid.Nil, fmt.Errorf("invalid token ID: %v", err)
+	}
+
+	return userID, tokenID, nil
+}
+
+// ValidateJWT is ParseJWT for callers that only need the user ID.
+func ValidateJWT(tokenString string, keys *Keyring) (uuid.UUID, error) {
+	claims, err := ParseJWT(tokenString, keys)
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	return claims.UserID, nil
 }
 
 func GetBearerToken(headers http.Header) (string, error) {
@@ -96,7 +261,12 @@ func GetBearerToken(headers http.Header) (string, error) {
 		return "", errors.New("Authorization header is missing")
 	}
 
-	tokenString := strings.TrimPrefix(authHeader, "Bearer ")
+	scheme, tokenString, found := strings.Cut(authHeader, " ")
+	if !found || !strings.EqualFold(scheme, "Bearer") {
+		return "", errors.New("Authorization header must use the Bearer scheme")
+	}
+
+	tokenString = strings.TrimSpace(tokenString)
 	if tokenString == "" {
 		return "", errors.New("Access Token is missing")
 	}
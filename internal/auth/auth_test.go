@@ -1,29 +1,38 @@
 package auth
 
 import (
+	"net/http"
 	"testing"
 	"time"
 
+	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
 )
 
 func TestMakeJWT(t *testing.T) {
 	userID := uuid.New()
-	secret := "test-secret"
+	keys, err := GenerateKeyring()
+	if err != nil {
+		t.Fatalf("GenerateKeyring failed: %v", err)
+	}
+	otherKeys, err := GenerateKeyring()
+	if err != nil {
+		t.Fatalf("GenerateKeyring failed: %v", err)
+	}
 
 	tests := []struct {
 		name          string
 		makeTokenFunc func() (string, error)
-		secretToUse   string
+		keysToUse     *Keyring
 		expectErr     bool
 		expectUserID  uuid.UUID
 	}{
 		{
 			name: "valid token",
 			makeTokenFunc: func() (string, error) {
-				return MakeJWT(userID, secret, time.Hour)
+				return MakeJWT(userID, RoleUser, keys, time.Hour)
 			},
-			secretToUse:  secret,
+			keysToUse:    keys,
 			expectErr:    false,
 			expectUserID: userID,
 		},
@@ -32,25 +41,40 @@ func TestMakeJWT(t *testing.T) {
 			makeTokenFunc: func() (string, error) {
 				return "invalid-token", nil
 			},
-			secretToUse: secret,
-			expectErr:   true,
+			keysToUse: keys,
+			expectErr: true,
 		},
 		{
 			name: "expired token",
 			makeTokenFunc: func() (string, error) {
-				return MakeJWT(userID, secret, -1*time.Hour)
+				return MakeJWT(userID, RoleUser, keys, -1*time.Hour)
 			},
-			secretToUse: secret,
-			expectErr:   true,
+			keysToUse: keys,
+			expectErr: true,
 		},
 
 		{
-			name: "wrong secret",
+			name: "unknown key",
 			makeTokenFunc: func() (string, error) {
-				return MakeJWT(userID, secret, time.Hour)
+				return MakeJWT(userID, RoleUser, keys, time.Hour)
 			},
-			secretToUse: "WRONG-SECRET",
-			expectErr:   true,
+			keysToUse: otherKeys,
+			expectErr: true,
+		},
+		{
+			name: "HS256 token",
+			makeTokenFunc: func() (string, error) {
+				token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.RegisteredClaims{
+					Issuer:    issuerType,
+					ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+					Subject:   userID.String(),
+				})
+				kid, _, _ := keys.signer()
+				token.Header["kid"] = kid
+				return token.SignedString([]byte("test-secret"))
+			},
+			keysToUse: keys,
+			expectErr: true,
 		},
 	}
 
@@ -61,7 +85,7 @@ func TestMakeJWT(t *testing.T) {
 				t.Fatalf("MakeJWT failed unexpectedly: %v", err)
 			}
 
-			parsedID, err := ValidateJWT(token, tc.secretToUse)
+			parsedID, err := ValidateJWT(token, tc.keysToUse)
 
 			if tc.expectErr {
 				if err == nil {
@@ -80,3 +104,91 @@ func TestMakeJWT(t *testing.T) {
 		})
 	}
 }
+
+func TestHashRefreshToken(t *testing.T) {
+	token, err := MakeRefreshToken()
+	if err != nil {
+		t.Fatalf("MakeRefreshToken failed: %v", err)
+	}
+
+	hash := HashRefreshToken(token)
+	if hash == token {
+		t.Fatalf("hash must not equal the token")
+	}
+
+	if HashRefreshToken(token) != hash {
+		t.Fatalf("hash must be deterministic for lookups")
+	}
+
+	// must stay in sync with the re-keying in 016_refresh_tokens_hashed.sql
+	want := "ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad"
+	if got := HashRefreshToken("abc"); got != want {
+		t.Fatalf("expected %s, got %s", want, got)
+	}
+}
+
+func TestGetBearerToken(t *testing.T) {
+	tests := []struct {
+		name      string
+		header    string
+		expectErr bool
+		expected  string
+	}{
+		{name: "valid", header: "Bearer abc.def", expected: "abc.def"},
+		{name: "lowercase scheme", header: "bearer abc.def", expected: "abc.def"},
+		{name: "missing", header: "", expectErr: true},
+		{name: "no token", header: "Bearer ", expectErr: true},
+		{name: "other scheme", header: "ApiKey abc", expectErr: true},
+		{name: "no scheme", header: "abc.def", expectErr: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			headers := http.Header{}
+			if tc.header != "" {
+				headers.Set("Authorization", tc.header)
+			}
+
+			token, err := GetBearerToken(headers)
+			if tc.expectErr {
+				if err == nil {
+					t.Fatalf("expected error but got none")
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if token != tc.expected {
+				t.Fatalf("expected %q, got %q", tc.expected, token)
+			}
+		})
+	}
+}
+
+func TestActionToken(t *testing.T) {
+	keys, err := GenerateKeyring()
+	if err != nil {
+		t.Fatalf("GenerateKeyring failed: %v", err)
+	}
+	userID, tokenID := uuid.New(), uuid.New()
+
+	token, err := MakeActionToken(userID, tokenID, PurposePasswordReset, keys, time.Hour)
+	if err != nil {
+		t.Fatalf("MakeActionToken failed: %v", err)
+	}
+
+	gotUser, gotToken, err := ParseActionToken(token, PurposePasswordReset, keys)
+	if err != nil || gotUser != userID || gotToken != tokenID {
+		t.Fatalf("expected %s/%s, got %s/%s, %v", userID, tokenID, gotUser, gotToken, err)
+	}
+
+	if _, _, err := ParseActionToken(token, PurposeVerifyEmail, keys); err == nil {
+		t.Fatalf("a reset token must not verify an email")
+	}
+
+	if _, err := ParseJWT(token, keys); err == nil {
+		t.Fatalf("a reset token must not pass as an access token")
+	}
+}
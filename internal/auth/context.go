@@ -0,0 +1,17 @@
+package auth
+
+import "context"
+
+type claimsKey struct{}
+
+// ContextWithClaims returns a copy of ctx carrying the caller's verified
+// claims.
+func ContextWithClaims(ctx context.Context, claims *Claims) context.Context {
+	return context.WithValue(ctx, claimsKey{}, claims)
+}
+
+// ClaimsFromContext returns the claims stored by ContextWithClaims, if any.
+func ClaimsFromContext(ctx context.Context) (*Claims, bool) {
+	claims, ok := ctx.Value(claimsKey{}).(*Claims)
+	return claims, ok
+}
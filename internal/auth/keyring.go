@@ -0,0 +1,205 @@
+package auth
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+var (
+	ErrUnknownKey   = errors.New("unknown signing key")
+	ErrNoSigningKey = errors.New("keyring has no signing key")
+)
+
+// Keyring holds the Ed25519 keys used for access tokens. One private key
+// signs new tokens; every public key in the ring can verify, so tokens signed
+// by a retiring key stay valid while a new key takes over.
+type Keyring struct {
+	mu         sync.RWMutex
+	signingKID string
+	signingKey ed25519.PrivateKey
+	keys       map[string]ed25519.PublicKey
+}
+
+func NewKeyring() *Keyring {
+	return &Keyring{
+		keys: map[string]ed25519.PublicKey{},
+	}
+}
+
+// GenerateKeyring returns a ring with a single random key. Tokens it signs do
+// not survive a restart, so it is only meant for development and tests.
+func GenerateKeyring() (*Keyring, error) {
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+
+	suffix := make([]byte, 4)
+	if _, err := rand.Read(suffix); err != nil {
+		return nil, err
+	}
+
+	ring := NewKeyring()
+	ring.SetSigningKey("ephemeral-"+hex.EncodeToString(suffix), priv)
+	return ring, nil
+}
+
+// LoadKeyring reads every *.pem file in dir. The file name without its
+// extension is the key's kid. PKCS#8 private keys can sign and verify, PKIX
+// public keys only verify. signingKID picks the signing key; it may be empty
+// when the directory holds exactly one private key.
+func LoadKeyring(dir, signingKID string) (*Keyring, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.pem"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(paths)
+
+	ring := NewKeyring()
+	private := map[string]ed25519.PrivateKey{}
+
+	for _, path := range paths {
+		kid := strings.TrimSuffix(filepath.Base(path), ".pem")
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+
+		block, _ := pem.Decode(data)
+		if block == nil {
+			return nil, fmt.Errorf("%s: no PEM block found", path)
+		}
+
+		switch block.Type {
+		case "PRIVATE KEY":
+			key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", path, err)
+			}
+			priv, ok := key.(ed25519.PrivateKey)
+			if !ok {
+				return nil, fmt.Errorf("%s: not an Ed25519 key", path)
+			}
+			private[kid] = priv
+			ring.AddVerificationKey(kid, priv.Public().(ed25519.PublicKey))
+		case "PUBLIC KEY":
+			key, err := x509.ParsePKIXPublicKey(block.Bytes)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", path, err)
+			}
+			pub, ok := key.(ed25519.PublicKey)
+			if !ok {
+				return nil, fmt.Errorf("%s: not an Ed25519 key", path)
+			}
+			ring.AddVerificationKey(kid, pub)
+		default:
+			return nil, fmt.Errorf("%s: unexpected PEM block %q", path, block.Type)
+		}
+	}
+
+	if signingKID == "" {
+		if len(private) != 1 {
+			return nil, fmt.Errorf("%w: found %d private keys in %s, set the signing kid explicitly", ErrNoSigningKey, len(private), dir)
+		}
+		for kid := range private {
+			signingKID = kid
+		}
+	}
+
+	priv, ok := private[signingKID]
+	if !ok {
+		return nil, fmt.Errorf("%w: no private key %q in %s", ErrNoSigningKey, signingKID, dir)
+	}
+	ring.SetSigningKey(signingKID, priv)
+
+	return ring, nil
+}
+
+// SetSigningKey makes key the one new tokens are signed with. Its public half
+// is added to the ring for verification.
+func (k *Keyring) SetSigningKey(kid string, key ed25519.PrivateKey) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	k.signingKID = kid
+	k.signingKey = key
+	k.keys[kid] = key.Public().(ed25519.PublicKey)
+}
+
+func (k *Keyring) AddVerificationKey(kid string, key ed25519.PublicKey) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	k.keys[kid] = key
+}
+
+func (k *Keyring) signer() (string, ed25519.PrivateKey, error) {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	if k.signingKey == nil {
+		return "", nil, ErrNoSigningKey
+	}
+	return k.signingKID, k.signingKey, nil
+}
+
+func (k *Keyring) verificationKey(kid string) (ed25519.PublicKey, error) {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	key, ok := k.keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownKey, kid)
+	}
+	return key, nil
+}
+
+// JWK is a public key in the JSON Web Key format of RFC 8037.
+type JWK struct {
+	Kty string `json:"kty"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+}
+
+type JWKSet struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWKS returns every verification key, sorted by kid.
+func (k *Keyring) JWKS() JWKSet {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	set := JWKSet{Keys: []JWK{}}
+	for kid, key := range k.keys {
+		set.Keys = append(set.Keys, JWK{
+			Kty: "OKP",
+			Crv: "Ed25519",
+			X:   base64.RawURLEncoding.EncodeToString(key),
+			Kid: kid,
+			Use: "sig",
+			Alg: "EdDSA",
+		})
+	}
+
+	sort.Slice(set.Keys, func(i, j int) bool {
+		return set.Keys[i].Kid < set.Keys[j].Kid
+	})
+
+	return set
+}
@@ -0,0 +1,108 @@
+package auth
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func writeKey(t *testing.T, dir, kid string, key interface{}) {
+	t.Helper()
+
+	var block *pem.Block
+	switch k := key.(type) {
+	case ed25519.PrivateKey:
+		der, err := x509.MarshalPKCS8PrivateKey(k)
+		if err != nil {
+			t.Fatalf("could not marshal private key: %v", err)
+		}
+		block = &pem.Block{Type: "PRIVATE KEY", Bytes: der}
+	case ed25519.PublicKey:
+		der, err := x509.MarshalPKIXPublicKey(k)
+		if err != nil {
+			t.Fatalf("could not marshal public key: %v", err)
+		}
+		block = &pem.Block{Type: "PUBLIC KEY", Bytes: der}
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, kid+".pem"), pem.EncodeToMemory(block), 0o600); err != nil {
+		t.Fatalf("could not write key: %v", err)
+	}
+}
+
+func TestKeyringRotation(t *testing.T) {
+	dir := t.TempDir()
+	userID := uuid.New()
+
+	oldPub, oldPriv, _ := ed25519.GenerateKey(rand.Reader)
+	_, newPriv, _ := ed25519.GenerateKey(rand.Reader)
+
+	writeKey(t, dir, "old", oldPriv)
+	before, err := LoadKeyring(dir, "")
+	if err != nil {
+		t.Fatalf("LoadKeyring failed: %v", err)
+	}
+
+	oldToken, err := MakeJWT(userID, RoleUser, before, time.Hour)
+	if err != nil {
+		t.Fatalf("MakeJWT failed: %v", err)
+	}
+
+	// rotate: the old key is demoted to verification only
+	writeKey(t, dir, "old", oldPub)
+	writeKey(t, dir, "new", newPriv)
+	after, err := LoadKeyring(dir, "")
+	if err != nil {
+		t.Fatalf("LoadKeyring failed: %v", err)
+	}
+
+	if id, err := ValidateJWT(oldToken, after); err != nil || id != userID {
+		t.Fatalf("token signed by the retired key should still validate, got %v", err)
+	}
+
+	newToken, err := MakeJWT(userID, RoleUser, after, time.Hour)
+	if err != nil {
+		t.Fatalf("MakeJWT failed: %v", err)
+	}
+	if _, err := ValidateJWT(newToken, before); !errors.Is(err, ErrUnknownKey) {
+		t.Fatalf("expected ErrUnknownKey, got %v", err)
+	}
+
+	jwks := after.JWKS()
+	if len(jwks.Keys) != 2 || jwks.Keys[0].Kid != "new" || jwks.Keys[1].Kid != "old" {
+		t.Fatalf("unexpected JWKS: %+v", jwks)
+	}
+}
+
+func TestLoadKeyringNeedsSigningKey(t *testing.T) {
+	dir := t.TempDir()
+
+	if _, err := LoadKeyring(dir, ""); !errors.Is(err, ErrNoSigningKey) {
+		t.Fatalf("expected ErrNoSigningKey for an empty directory, got %v", err)
+	}
+
+	_, a, _ := ed25519.GenerateKey(rand.Reader)
+	_, b, _ := ed25519.GenerateKey(rand.Reader)
+	writeKey(t, dir, "a", a)
+	writeKey(t, dir, "b", b)
+
+	if _, err := LoadKeyring(dir, ""); !errors.Is(err, ErrNoSigningKey) {
+		t.Fatalf("expected ErrNoSigningKey when the signing key is ambiguous, got %v", err)
+	}
+
+	ring, err := LoadKeyring(dir, "b")
+	if err != nil {
+		t.Fatalf("LoadKeyring failed: %v", err)
+	}
+	if kid, _, _ := ring.signer(); kid != "b" {
+		t.Fatalf("expected signing kid b, got %s", kid)
+	}
+}
@@ -0,0 +1,47 @@
+package auth
+
+import "fmt"
+
+type Role string
+
+const (
+	RoleUser      Role = "user"
+	RoleModerator Role = "moderator"
+	RoleAdmin     Role = "admin"
+)
+
+// Permission names one thing a role may do. Handlers check permissions, not
+// roles, so what a role grants can change in one place.
+type Permission string
+
+const (
+	PermViewMetrics    Permission = "metrics:read"
+	PermModerate       Permission = "moderation:manage"
+	PermManageRoles    Permission = "users:manage_roles"
+	PermManageWebhooks Permission = "webhooks:manage"
+	PermResetData      Permission = "data:reset"
+)
+
+var rolePermissions = map[Role][]Permission{
+	RoleUser:      {},
+	RoleModerator: {PermModerate},
+	RoleAdmin:     {PermViewMetrics, PermModerate, PermManageRoles, PermManageWebhooks, PermResetData},
+}
+
+func ParseRole(s string) (Role, error) {
+	role := Role(s)
+	if _, ok := rolePermissions[role]; !ok {
+		return "", fmt.Errorf("unknown role %q", s)
+	}
+	return role, nil
+}
+
+// Can reports whether the role grants perm. Unknown roles grant nothing.
+func (r Role) Can(perm Permission) bool {
+	for _, p := range rolePermissions[r] {
+		if p == perm {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,64 @@
+package auth
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestRoleCan(t *testing.T) {
+	tests := []struct {
+		role     Role
+		perm     Permission
+		expected bool
+	}{
+		{RoleUser, PermModerate, false},
+		{RoleUser, PermViewMetrics, false},
+		{RoleModerator, PermModerate, true},
+		{RoleModerator, PermManageRoles, false},
+		{RoleAdmin, PermViewMetrics, true},
+		{RoleAdmin, PermManageRoles, true},
+		{RoleAdmin, PermManageWebhooks, true},
+		{RoleModerator, PermManageWebhooks, false},
+		{RoleAdmin, PermResetData, true},
+		{RoleModerator, PermResetData, false},
+		{Role("root"), PermViewMetrics, false},
+	}
+
+	for _, tc := range tests {
+		if got := tc.role.Can(tc.perm); got != tc.expected {
+			t.Errorf("%s.Can(%s): expected %v, got %v", tc.role, tc.perm, tc.expected, got)
+		}
+	}
+}
+
+func TestParseRole(t *testing.T) {
+	if role, err := ParseRole("moderator"); err != nil || role != RoleModerator {
+		t.Fatalf("expected moderator, got %q, %v", role, err)
+	}
+
+	if _, err := ParseRole("superuser"); err == nil {
+		t.Fatalf("expected an error for an unknown role")
+	}
+}
+
+func TestRoleClaim(t *testing.T) {
+	keys, err := GenerateKeyring()
+	if err != nil {
+		t.Fatalf("GenerateKeyring failed: %v", err)
+	}
+
+	token, err := MakeJWT(uuid.New(), RoleAdmin, keys, time.Hour)
+	if err != nil {
+		t.Fatalf("MakeJWT failed: %v", err)
+	}
+
+	claims, err := ParseJWT(token, keys)
+	if err != nil {
+		t.Fatalf("ParseJWT failed: %v", err)
+	}
+	if claims.Role != RoleAdmin {
+		t.Fatalf("expected role admin, got %q", claims.Role)
+	}
+}
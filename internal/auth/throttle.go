@@ -0,0 +1,36 @@
+package auth
+
+import "time"
+
+// Throttle is a backoff policy for repeated failures, such as wrong
+// passwords. The first Free failures cost nothing; after that each failure
+// doubles the wait, starting at Base, and from LockAt failures on the wait is
+// the full Lockout.
+type Throttle struct {
+	Free    int32
+	Base    time.Duration
+	LockAt  int32
+	Lockout time.Duration
+}
+
+// Delay returns how long to refuse attempts after the given number of
+// consecutive failures.
+func (t Throttle) Delay(failures int32) time.Duration {
+	if failures <= t.Free {
+		return 0
+	}
+	if failures >= t.LockAt {
+		return t.Lockout
+	}
+
+	delay := t.Base
+	for i := t.Free + 1; i < failures && delay < t.Lockout; i++ {
+		delay *= 2
+	}
+	return min(delay, t.Lockout)
+}
+
+// Locks reports whether failures is the count at which the lockout starts.
+func (t Throttle) Locks(failures int32) bool {
+	return failures == t.LockAt
+}
@@ -0,0 +1,51 @@
+package auth
+
+import (
+	"testing"
+	"time"
+)
+
+func TestThrottleDelay(t *testing.T) {
+	throttle := Throttle{
+		Free:    3,
+		Base:    time.Second,
+		LockAt:  10,
+		Lockout: 15 * time.Minute,
+	}
+
+	tests := []struct {
+		failures int32
+		want     time.Duration
+	}{
+		{0, 0},
+		{3, 0},
+		{4, time.Second},
+		{5, 2 * time.Second},
+		{9, 32 * time.Second},
+		{10, 15 * time.Minute},
+		{50, 15 * time.Minute},
+	}
+
+	for _, tc := range tests {
+		if got := throttle.Delay(tc.failures); got != tc.want {
+			t.Errorf("Delay(%d) = %s, want %s", tc.failures, got, tc.want)
+		}
+	}
+}
+
+func TestThrottleDelayIsCapped(t *testing.T) {
+	throttle := Throttle{
+		Free:    0,
+		Base:    time.Minute,
+		LockAt:  100,
+		Lockout: time.Hour,
+	}
+
+	if got := throttle.Delay(99); got != time.Hour {
+		t.Fatalf("expected the backoff to stop at the lockout, got %s", got)
+	}
+
+	if !throttle.Locks(100) || throttle.Locks(101) {
+		t.Fatalf("Locks should only report the failure that starts the lockout")
+	}
+}
@@ -0,0 +1,113 @@
+package auth
+
+import (
+	"encoding/base32"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// rfc6238Secret is the SHA-1 key from RFC 6238 appendix B.
+var rfc6238Secret = base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString([]byte("12345678901234567890"))
+
+func TestTOTPCodeRFC6238(t *testing.T) {
+	tests := []struct {
+		unix int64
+		code string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+	}
+
+	for _, tc := range tests {
+		got, err := TOTPCode(rfc6238Secret, TOTPStep(time.Unix(tc.unix, 0)))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if got != tc.code {
+			t.Errorf("at %d: expected %s, got %s", tc.unix, tc.code, got)
+		}
+	}
+}
+
+func TestValidateTOTP(t *testing.T) {
+	now := time.Unix(1234567890, 0)
+	code, _ := TOTPCode(rfc6238Secret, TOTPStep(now))
+
+	step, err := ValidateTOTP(rfc6238Secret, code, now.Add(totpPeriod*time.Second))
+	if err != nil {
+		t.Fatalf("a code from the previous step should be accepted: %v", err)
+	}
+	if step != TOTPStep(now) {
+		t.Fatalf("expected step %d, got %d", TOTPStep(now), step)
+	}
+
+	if _, err := ValidateTOTP(rfc6238Secret, code, now.Add(3*totpPeriod*time.Second)); !errors.Is(err, ErrInvalidTOTP) {
+		t.Fatalf("expected ErrInvalidTOTP for an old code, got %v", err)
+	}
+
+	if _, err := ValidateTOTP(rfc6238Secret, "12345", now); !errors.Is(err, ErrInvalidTOTP) {
+		t.Fatalf("expected ErrInvalidTOTP for a short code, got %v", err)
+	}
+}
+
+func TestTOTPURI(t *testing.T) {
+	uri := TOTPURI("Chirpy", "user@example.com", "ABC")
+	if !strings.HasPrefix(uri, "otpauth://totp/Chirpy:user@example.com?") {
+		t.Fatalf("unexpected URI %s", uri)
+	}
+	if !strings.Contains(uri, "secret=ABC") || !strings.Contains(uri, "issuer=Chirpy") {
+		t.Fatalf("URI is missing parameters: %s", uri)
+	}
+}
+
+func TestRecoveryCodes(t *testing.T) {
+	codes, err := GenerateRecoveryCodes()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(codes) != recoveryCodeCount {
+		t.Fatalf("expected %d codes, got %d", recoveryCodeCount, len(codes))
+	}
+
+	code := codes[0]
+	if !IsRecoveryCode(code) || IsRecoveryCode("123456") {
+		t.Fatalf("recovery and TOTP codes must be told apart")
+	}
+
+	if HashRecoveryCode(code) != HashRecoveryCode(" "+strings.ToUpper(strings.ReplaceAll(code, "-", ""))) {
+		t.Fatalf("hash should ignore case, spaces and dashes")
+	}
+}
+
+func TestMFAChallengeIsNotAnAccessToken(t *testing.T) {
+	keys, err := GenerateKeyring()
+	if err != nil {
+		t.Fatalf("GenerateKeyring failed: %v", err)
+	}
+	userID := uuid.New()
+
+	challenge, err := MakeMFAChallenge(userID, keys, time.Minute)
+	if err != nil {
+		t.Fatalf("MakeMFAChallenge failed: %v", err)
+	}
+
+	if _, err := ParseJWT(challenge, keys); err == nil {
+		t.Fatalf("an MFA challenge must not pass as an access token")
+	}
+
+	if id, err := ParseMFAChallenge(challenge, keys); err != nil || id != userID {
+		t.Fatalf("expected %s, got %s, %v", userID, id, err)
+	}
+
+	access, _ := MakeJWT(userID, RoleUser, keys, time.Minute)
+	if _, err := ParseMFAChallenge(access, keys); err == nil {
+		t.Fatalf("an access token must not pass as an MFA challenge")
+	}
+}
@@ -0,0 +1,62 @@
+// Package entitlements says what each membership tier may do. Handlers look
+// up a user's Entitlements and check its fields; the rules themselves live
+// only in the tiers table below.
+package entitlements
+
+type Tier string
+
+const (
+	Free Tier = "free"
+	Red  Tier = "chirpy_red"
+)
+
+type Entitlements struct {
+	Tier Tier `json:"tier"`
+	// MaxChirpLength is the longest chirp body, in characters.
+	MaxChirpLength int `json:"max_chirp_length"`
+	// EditChirps allows changing a chirp after posting it.
+	EditChirps bool `json:"edit_chirps"`
+	// MaxScheduledChirps is how many chirps may wait to be published at
+	// once. Zero means the tier cannot schedule chirps.
+	MaxScheduledChirps int `json:"max_scheduled_chirps"`
+	// ChirpsPerHour caps new chirps over any rolling hour.
+	ChirpsPerHour int `json:"chirps_per_hour"`
+}
+
+var tiers = map[Tier]Entitlements{
+	Free: {
+		Tier:               Free,
+		MaxChirpLength:     140,
+		EditChirps:         false,
+		MaxScheduledChirps: 0,
+		ChirpsPerHour:      30,
+	},
+	Red: {
+		Tier:               Red,
+		MaxChirpLength:     280,
+		EditChirps:         true,
+		MaxScheduledChirps: 50,
+		ChirpsPerHour:      300,
+	},
+}
+
+// TierFor returns the tier of a user by their Chirpy Red flag.
+func TierFor(isChirpyRed bool) Tier {
+	if isChirpyRed {
+		return Red
+	}
+	return Free
+}
+
+// For returns the entitlements of tier. Unknown tiers get the free ones.
+func For(tier Tier) Entitlements {
+	if e, ok := tiers[tier]; ok {
+		return e
+	}
+	return tiers[Free]
+}
+
+// CanScheduleChirps reports whether the tier may schedule chirps at all.
+func (e Entitlements) CanScheduleChirps() bool {
+	return e.MaxScheduledChirps > 0
+}
@@ -0,0 +1,32 @@
+package entitlements
+
+import "testing"
+
+func TestFor(t *testing.T) {
+	free := For(TierFor(false))
+	red := For(TierFor(true))
+
+	if free.Tier != Free || red.Tier != Red {
+		t.Fatalf("expected the free and red tiers, got %s and %s", free.Tier, red.Tier)
+	}
+
+	if free.MaxChirpLength != 140 {
+		t.Fatalf("free chirps should keep the classic 140 limit, got %d", free.MaxChirpLength)
+	}
+
+	if free.EditChirps || free.CanScheduleChirps() {
+		t.Fatalf("free members should not edit or schedule chirps")
+	}
+
+	if !red.EditChirps || !red.CanScheduleChirps() {
+		t.Fatalf("red members should edit and schedule chirps")
+	}
+
+	if red.MaxChirpLength <= free.MaxChirpLength || red.ChirpsPerHour <= free.ChirpsPerHour {
+		t.Fatalf("red limits should be above free ones")
+	}
+
+	if For("platinum") != free {
+		t.Fatalf("unknown tiers should fall back to free")
+	}
+}
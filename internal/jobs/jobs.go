@@ -0,0 +1,286 @@
+// Package jobs runs background work queued in the jobs table.
+//
+// Handlers call Enqueue with the *database.Queries of the transaction that
+// makes their change, so a job exists if and only if that change commits.
+// A Runner claims due jobs with SELECT ... FOR UPDATE SKIP LOCKED, so any
+// number of servers can share the queue, and retries failures with
+// exponential backoff. Jobs run at least once: a worker that dies mid-job
+// leaves it to be claimed again when its lease runs out, so handlers must be
+// safe to repeat.
+package jobs
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/adi290491/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+const (
+	DefaultMaxAttempts  = 10
+	DefaultWorkers      = 4
+	DefaultTimeout      = time.Minute
+	DefaultPollInterval = 2 * time.Second
+
+	backoffBase    = 15 * time.Second
+	backoffMax     = time.Hour
+	maxErrorLength = 500
+)
+
+// Handler runs one job. Returning an error retries the job later unless it
+// is wrapped with Permanent.
+type Handler func(ctx context.Context, payload json.RawMessage) error
+
+type permanentError struct{ err error }
+
+func (e permanentError) Error() string { return e.err.Error() }
+func (e permanentError) Unwrap() error { return e.err }
+
+// Permanent marks err as one that retrying cannot fix, such as a payload
+// that does not decode. The job fails at once.
+func Permanent(err error) error {
+	return permanentError{err}
+}
+
+// Option changes how a job is enqueued.
+type Option func(*database.EnqueueJobParams)
+
+// At delays the job until t.
+func At(t time.Time) Option {
+	return func(p *database.EnqueueJobParams) {
+		p.RunAt = t.UTC()
+	}
+}
+
+// MaxAttempts overrides DefaultMaxAttempts.
+func MaxAttempts(n int32) Option {
+	return func(p *database.EnqueueJobParams) {
+		p.MaxAttempts = n
+	}
+}
+
+func newJob(kind string, payload any, opts []Option) (database.EnqueueJobParams, error) {
+	raw, err := json.Marshal(payload)
+	if err != nil {
+		return database.EnqueueJobParams{}, fmt.Errorf("encoding %s job: %w", kind, err)
+	}
+
+	p := database.EnqueueJobParams{
+		Kind:        kind,
+		Payload:     raw,
+		MaxAttempts: DefaultMaxAttempts,
+		RunAt:       time.Now().UTC(),
+	}
+	for _, opt := range opts {
+		opt(&p)
+	}
+	if p.MaxAttempts < 1 {
+		p.MaxAttempts = 1
+	}
+
+	return p, nil
+}
+
+// Enqueue queues a job of the given kind with payload encoded as JSON.
+func Enqueue(ctx context.Context, q *database.Queries, kind string, payload any, opts ...Option) (uuid.UUID, error) {
+	p, err := newJob(kind, payload, opts)
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	return q.EnqueueJob(ctx, p)
+}
+
+type lastAttemptKey struct{}
+
+// LastAttempt reports whether the job running with ctx fails for good if
+// this attempt does, so its handler can clean up what it leaves behind.
+func LastAttempt(ctx context.Context) bool {
+	last, _ := ctx.Value(lastAttemptKey{}).(bool)
+	return last
+}
+
+// Backoff is how long to wait before retrying after the given number of
+// failed attempts: 15s, 30s, 1m and so on, capped at an hour.
+func Backoff(attempts int32) time.Duration {
+	if attempts < 1 {
+		return 0
+	}
+
+	d := backoffBase
+	for i := int32(1); i < attempts; i++ {
+		d *= 2
+		if d >= backoffMax {
+			return backoffMax
+		}
+	}
+	return d
+}
+
+// Runner claims jobs and runs them on a pool of workers. Set the exported
+// fields before calling Run.
+type Runner struct {
+	Workers      int
+	Timeout      time.Duration
+	PollInterval time.Duration
+
+	db       *database.Queries
+	handlers map[string]Handler
+}
+
+func NewRunner(db *database.Queries) *Runner {
+	return &Runner{
+		Workers:      DefaultWorkers,
+		Timeout:      DefaultTimeout,
+		PollInterval: DefaultPollInterval,
+		db:           db,
+		handlers:     map[string]Handler{},
+	}
+}
+
+// Handle registers h for jobs of the given kind.
+func (r *Runner) Handle(kind string, h Handler) {
+	r.handlers[kind] = h
+}
+
+// Run works through the queue until ctx is done, then waits for the jobs it
+// already started. Those keep running with their own timeout rather than
+// being cut off, so a graceful shutdown does not waste an attempt.
+func (r *Runner) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.PollInterval)
+	defer ticker.Stop()
+
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
+	slots := make(chan struct{}, r.Workers)
+	// a finished job wakes the loop so its slot is refilled at once
+	freed := make(chan struct{}, 1)
+
+	for {
+		free := cap(slots) - len(slots)
+		claimed := 0
+
+		if free > 0 {
+			jobs, err := r.db.ClaimJobs(ctx, database.ClaimJobsParams{
+				Limit: int32(free),
+				// the lease outlasts the job's timeout, so it is not claimed
+				// twice while it still runs
+				LeaseUntil: time.Now().UTC().Add(2 * r.Timeout),
+			})
+			if err != nil && ctx.Err() == nil {
+				log.Printf("could not claim jobs: %v", err)
+			}
+
+			for _, job := range jobs {
+				slots <- struct{}{}
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					r.run(context.WithoutCancel(ctx), job)
+					<-slots
+					select {
+					case freed <- struct{}{}:
+					default:
+					}
+				}()
+			}
+			claimed = len(jobs)
+		}
+
+		// a full claim means more may be waiting
+		if free > 0 && claimed == free {
+			select {
+			case <-ctx.Done():
+				return
+			default:
+			}
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-freed:
+		}
+	}
+}
+
+func (r *Runner) run(ctx context.Context, job database.Job) {
+	jobCtx, cancel := context.WithTimeout(ctx, r.Timeout)
+	err := r.execute(jobCtx, job)
+	cancel()
+
+	if err == nil {
+		if err := r.db.CompleteJob(ctx, job.ID); err != nil {
+			log.Printf("could not complete job %s: %v", job.ID, err)
+		}
+		return
+	}
+
+	msg := errorText(err)
+	lastError := sql.NullString{String: msg, Valid: true}
+
+	var permanent permanentError
+	if errors.As(err, &permanent) || job.Attempts >= job.MaxAttempts {
+		log.Printf("job %s (%s) failed after %d attempts: %s", job.ID, job.Kind, job.Attempts, msg)
+		err = r.db.FailJob(ctx, database.FailJobParams{
+			ID:        job.ID,
+			LastError: lastError,
+		})
+	} else {
+		err = r.db.RetryJob(ctx, database.RetryJobParams{
+			ID:        job.ID,
+			RunAt:     time.Now().UTC().Add(Backoff(job.Attempts)),
+			LastError: lastError,
+		})
+	}
+	if err != nil {
+		log.Printf("could not record failure of job %s: %v", job.ID, err)
+	}
+}
+
+// errorText is err's message as it is stored in last_error. Handler errors
+// can quote replies from other servers, so the text is made valid UTF-8,
+// which Postgres insists on, and cut to maxErrorLength on a character
+// boundary; otherwise the failure could not be recorded and the job would be
+// claimed again forever.
+func errorText(err error) string {
+	msg := strings.ToValidUTF8(err.Error(), "")
+	if len(msg) <= maxErrorLength {
+		return msg
+	}
+
+	end := maxErrorLength
+	for end > 0 && !utf8.RuneStart(msg[end]) {
+		end--
+	}
+	return msg[:end]
+}
+
+// execute runs the job's handler, turning a panic into a failed attempt.
+func (r *Runner) execute(ctx context.Context, job database.Job) (err error) {
+	h, ok := r.handlers[job.Kind]
+	if !ok {
+		return Permanent(fmt.Errorf("no handler for job kind %q", job.Kind))
+	}
+
+	defer func() {
+		if p := recover(); p != nil {
+			err = fmt.Errorf("job panicked: %v", p)
+		}
+	}()
+
+	ctx = context.WithValue(ctx, lastAttemptKey{}, job.Attempts >= job.MaxAttempts)
+	return h(ctx, job.Payload)
+}
@@ -0,0 +1,158 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/adi290491/chirpy/internal/database"
+)
+
+func TestNewJob(t *testing.T) {
+	before := time.Now().UTC()
+
+	p, err := newJob("mail.send", map[string]string{"to": "a@example.com"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if p.Kind != "mail.send" || string(p.Payload) != `{"to":"a@example.com"}` {
+		t.Errorf("unexpected job %+v", p)
+	}
+	if p.MaxAttempts != DefaultMaxAttempts {
+		t.Errorf("expected %d attempts, got %d", DefaultMaxAttempts, p.MaxAttempts)
+	}
+	if p.RunAt.Before(before) || p.RunAt.After(time.Now().UTC()) {
+		t.Errorf("expected the job to be due now, got %v", p.RunAt)
+	}
+
+	later := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	p, err = newJob("mail.send", nil, []Option{At(later), MaxAttempts(0)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !p.RunAt.Equal(later) {
+		t.Errorf("expected the job to run at %v, got %v", later, p.RunAt)
+	}
+	if p.MaxAttempts != 1 {
+		t.Errorf("expected at least one attempt, got %d", p.MaxAttempts)
+	}
+
+	if _, err := newJob("bad", func() {}, nil); err == nil {
+		t.Error("expected a payload that cannot be encoded to fail")
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	tests := []struct {
+		attempts int32
+		want     time.Duration
+	}{
+		{0, 0},
+		{1, 15 * time.Second},
+		{2, 30 * time.Second},
+		{3, time.Minute},
+		{8, 32 * time.Minute},
+		{9, time.Hour},
+		{50, time.Hour},
+	}
+
+	for _, tc := range tests {
+		if got := Backoff(tc.attempts); got != tc.want {
+			t.Errorf("Backoff(%d): expected %v, got %v", tc.attempts, tc.want, got)
+		}
+	}
+}
+
+func TestExecute(t *testing.T) {
+	r := NewRunner(nil)
+	errBoom := errors.New("boom")
+
+	r.Handle("ok", func(ctx context.Context, payload json.RawMessage) error {
+		if string(payload) != `{"n":1}` {
+			return errors.New("wrong payload")
+		}
+		return nil
+	})
+	r.Handle("fails", func(context.Context, json.RawMessage) error { return errBoom })
+	r.Handle("bad", func(context.Context, json.RawMessage) error { return Permanent(errBoom) })
+	r.Handle("panics", func(context.Context, json.RawMessage) error { panic("oops") })
+
+	run := func(kind string) error {
+		return r.execute(context.Background(), database.Job{Kind: kind, Payload: json.RawMessage(`{"n":1}`)})
+	}
+
+	if err := run("ok"); err != nil {
+		t.Errorf("expected success, got %v", err)
+	}
+
+	var permanent permanentError
+	if err := run("fails"); !errors.Is(err, errBoom) || errors.As(err, &permanent) {
+		t.Errorf("expected a retryable error, got %v", err)
+	}
+	if err := run("bad"); !errors.Is(err, errBoom) || !errors.As(err, &permanent) {
+		t.Errorf("expected a permanent error wrapping the cause, got %v", err)
+	}
+	if err := run("panics"); err == nil || errors.As(err, &permanent) {
+		t.Errorf("expected a panic to be a retryable error, got %v", err)
+	}
+	if err := run("unknown"); !errors.As(err, &permanent) {
+		t.Errorf("expected an unknown kind to fail permanently, got %v", err)
+	}
+}
+
+func TestLastAttempt(t *testing.T) {
+	r := NewRunner(nil)
+
+	var last bool
+	r.Handle("check", func(ctx context.Context, _ json.RawMessage) error {
+		last = LastAttempt(ctx)
+		return nil
+	})
+
+	tests := []struct {
+		attempts, maxAttempts int32
+		want                  bool
+	}{
+		{1, 3, false},
+		{3, 3, true},
+		{1, 1, true},
+	}
+
+	for _, tc := range tests {
+		job := database.Job{Kind: "check", Attempts: tc.attempts, MaxAttempts: tc.maxAttempts}
+		if err := r.execute(context.Background(), job); err != nil {
+			t.Fatal(err)
+		}
+		if last != tc.want {
+			t.Errorf("attempt %d of %d: expected LastAttempt %v, got %v", tc.attempts, tc.maxAttempts, tc.want, last)
+		}
+	}
+
+	if LastAttempt(context.Background()) {
+		t.Error("expected a context outside a job not to be a last attempt")
+	}
+}
+
+func TestErrorText(t *testing.T) {
+	if got := errorText(errors.New("boom")); got != "boom" {
+		t.Errorf("expected a short message to be kept, got %q", got)
+	}
+
+	// 2-byte characters put a character across the byte limit
+	long := errorText(errors.New("x" + strings.Repeat("é", maxErrorLength)))
+	if !utf8.ValidString(long) {
+		t.Errorf("expected valid UTF-8, got %q", long)
+	}
+	if len(long) != maxErrorLength-1 {
+		t.Errorf("expected the message to be cut to %d bytes, got %d", maxErrorLength-1, len(long))
+	}
+
+	if got := errorText(errors.New("550 \xffrejected")); got != "550 rejected" {
+		t.Errorf("expected invalid bytes to be dropped, got %q", got)
+	}
+}
@@ -0,0 +1,36 @@
+package mail
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"time"
+)
+
+// File writes each message to its own .eml file in Dir instead of sending it,
+// for local development.
+type File struct {
+	Dir  string
+	From string
+
+	seq atomic.Int64
+}
+
+func NewFile(dir, from string) (*File, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &File{Dir: dir, From: from}, nil
+}
+
+func (f *File) Send(ctx context.Context, msg Message) error {
+	if err := checkHeaders(f.From, msg.To, msg.Subject); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	name := fmt.Sprintf("%s-%04d.eml", now.UTC().Format("20060102T150405.000000000"), f.seq.Add(1))
+	return os.WriteFile(filepath.Join(f.Dir, name), format(f.From, msg, now), 0o600)
+}
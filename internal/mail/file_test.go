@@ -0,0 +1,46 @@
+package mail
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFile(t *testing.T) {
+	dir := t.TempDir()
+	mailer, err := NewFile(dir, "chirpy@example.com")
+	if err != nil {
+		t.Fatalf("NewFile failed: %v", err)
+	}
+
+	err = mailer.Send(context.Background(), Message{
+		To:      "user@example.com",
+		Subject: "Hello",
+		Body:    "line one\nline two",
+	})
+	if err != nil {
+		t.Fatalf("Send failed: %v", err)
+	}
+
+	files, _ := filepath.Glob(filepath.Join(dir, "*.eml"))
+	if len(files) != 1 {
+		t.Fatalf("expected one message, got %d", len(files))
+	}
+
+	data, _ := os.ReadFile(files[0])
+	for _, want := range []string{"To: user@example.com\r\n", "Subject: Hello\r\n", "\r\n\r\nline one\r\nline two"} {
+		if !strings.Contains(string(data), want) {
+			t.Fatalf("message is missing %q:\n%s", want, data)
+		}
+	}
+
+	err = mailer.Send(context.Background(), Message{
+		To:      "user@example.com\r\nBcc: victim@example.com",
+		Subject: "Hello",
+	})
+	if err == nil {
+		t.Fatalf("expected header injection to be rejected")
+	}
+}
@@ -0,0 +1,18 @@
+package mail
+
+import (
+	"context"
+	"log"
+)
+
+// Log prints messages to the standard logger instead of sending them.
+type Log struct{}
+
+func (Log) Send(ctx context.Context, msg Message) error {
+	if err := checkHeaders(msg.To, msg.Subject); err != nil {
+		return err
+	}
+
+	log.Printf("mail to %s: %s\n%s", msg.To, msg.Subject, msg.Body)
+	return nil
+}
@@ -0,0 +1,38 @@
+package mail
+
+import (
+	"context"
+	"net"
+	"net/smtp"
+	"time"
+)
+
+// SMTP sends mail through a relay. net/smtp upgrades to TLS with STARTTLS
+// when the server offers it, and PlainAuth refuses to send credentials over
+// an unencrypted connection to anything but localhost.
+type SMTP struct {
+	Host     string
+	Port     string
+	Username string
+	Password string
+	From     string
+}
+
+func (s SMTP) Send(ctx context.Context, msg Message) error {
+	if err := checkHeaders(s.From, msg.To, msg.Subject); err != nil {
+		return err
+	}
+
+	var a smtp.Auth
+	if s.Username != "" {
+		a = smtp.PlainAuth("", s.Username, s.Password, s.Host)
+	}
+
+	// smtp.SendMail cannot be cancelled, so honour ctx before starting
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	addr := net.JoinHostPort(s.Host, s.Port)
+	return smtp.SendMail(addr, a, s.From, []string{msg.To}, format(s.From, msg, time.Now()))
+}
@@ -0,0 +1,123 @@
+package media
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+)
+
+const exifOrientationTag = 0x0112
+
+// jpegOrientation reads the EXIF Orientation tag of a JPEG, 1 to 8, which
+// says how the camera was held. Phones store portrait photos sideways and
+// set it to 6 or 8, so it has to be applied before the EXIF is dropped. It
+// is 1, upright, when the tag is missing or unreadable.
+func jpegOrientation(data []byte) int {
+	pos := 2 // SOI
+	for pos+4 <= len(data) && data[pos] == 0xFF {
+		marker := data[pos+1]
+		// start of scan: the metadata segments are all before it
+		if marker == 0xDA || marker == 0xD9 {
+			break
+		}
+
+		n := int(binary.BigEndian.Uint16(data[pos+2:]))
+		if n < 2 || pos+2+n > len(data) {
+			break
+		}
+		if marker == 0xE1 {
+			if o := exifOrientation(data[pos+4 : pos+2+n]); o != 0 {
+				return o
+			}
+		}
+		pos += 2 + n
+	}
+
+	return 1
+}
+
+// exifOrientation finds the Orientation tag in the first IFD of an APP1
+// segment's payload, returning 0 if there is none.
+func exifOrientation(segment []byte) int {
+	tiff, ok := bytes.CutPrefix(segment, []byte("Exif\x00\x00"))
+	if !ok || len(tiff) < 8 {
+		return 0
+	}
+
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return 0
+	}
+
+	ifd := order.Uint32(tiff[4:])
+	if ifd > uint32(len(tiff)-2) {
+		return 0
+	}
+	entries := tiff[ifd+2:]
+	for i := range int(order.Uint16(tiff[ifd:])) {
+		entry := entries[min(12*i, len(entries)):]
+		if len(entry) < 12 {
+			break
+		}
+		if order.Uint16(entry) != exifOrientationTag {
+			continue
+		}
+
+		// a SHORT, stored in the first two bytes of the value field
+		if order.Uint16(entry[2:]) != 3 {
+			return 0
+		}
+		if o := int(order.Uint16(entry[8:])); o >= 1 && o <= 8 {
+			return o
+		}
+		return 0
+	}
+
+	return 0
+}
+
+// orient turns src the way an EXIF orientation says it should be shown.
+func orient(src image.Image, orientation int) image.Image {
+	if orientation < 2 || orientation > 8 {
+		return src
+	}
+
+	b := src.Bounds()
+	w, h := b.Dx(), b.Dy()
+
+	dw, dh := w, h
+	if orientation >= 5 {
+		dw, dh = h, w
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, dw, dh))
+
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			var dx, dy int
+			switch orientation {
+			case 2: // mirrored
+				dx, dy = w-1-x, y
+			case 3: // upside down
+				dx, dy = w-1-x, h-1-y
+			case 4: // upside down and mirrored
+				dx, dy = x, h-1-y
+			case 5: // on its side and mirrored
+				dx, dy = y, x
+			case 6: // turned 90° clockwise to be upright
+				dx, dy = h-1-y, x
+			case 7:
+				dx, dy = h-1-y, w-1-x
+			case 8: // turned 90° counter-clockwise to be upright
+				dx, dy = y, w-1-x
+			}
+			dst.Set(dx, dy, src.At(b.Min.X+x, b.Min.Y+y))
+		}
+	}
+
+	return dst
+}
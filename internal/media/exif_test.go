@@ -0,0 +1,71 @@
+package media
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"testing"
+)
+
+func TestJPEGOrientation(t *testing.T) {
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, testImage(4, 4), nil); err != nil {
+		t.Fatalf("could not encode fixture: %v", err)
+	}
+	plain := buf.Bytes()
+
+	if got := jpegOrientation(plain); got != 1 {
+		t.Errorf("expected no tag to mean upright, got %d", got)
+	}
+	if got := jpegOrientation(withExif(t, plain)); got != 1 {
+		t.Errorf("expected Exif without the tag to mean upright, got %d", got)
+	}
+	for _, o := range []byte{3, 6, 8} {
+		if got := jpegOrientation(withOrientation(t, plain, o)); got != int(o) {
+			t.Errorf("expected orientation %d, got %d", o, got)
+		}
+	}
+
+	// little-endian, as some cameras write it
+	little := []byte("Exif\x00\x00II\x2a\x00\x08\x00\x00\x00\x01\x00\x12\x01\x03\x00\x01\x00\x00\x00\x08\x00\x00\x00\x00\x00\x00\x00")
+	if got := exifOrientation(little); got != 8 {
+		t.Errorf("expected little-endian orientation 8, got %d", got)
+	}
+	if got := exifOrientation(little[:20]); got != 0 {
+		t.Errorf("expected a truncated segment to have no orientation, got %d", got)
+	}
+}
+
+func TestOrient(t *testing.T) {
+	// a 3x2 image marked at its top-left corner
+	src := image.NewRGBA(image.Rect(0, 0, 3, 2))
+	mark := color.RGBA{R: 255, A: 255}
+	src.Set(0, 0, mark)
+
+	tests := []struct {
+		orientation int
+		w, h        int
+		x, y        int
+	}{
+		{1, 3, 2, 0, 0},
+		{2, 3, 2, 2, 0},
+		{3, 3, 2, 2, 1},
+		{4, 3, 2, 0, 1},
+		{5, 2, 3, 0, 0},
+		{6, 2, 3, 1, 0},
+		{7, 2, 3, 1, 2},
+		{8, 2, 3, 0, 2},
+	}
+
+	for _, tc := range tests {
+		got := orient(src, tc.orientation)
+		if b := got.Bounds(); b.Dx() != tc.w || b.Dy() != tc.h {
+			t.Errorf("orientation %d: expected %dx%d, got %dx%d", tc.orientation, tc.w, tc.h, b.Dx(), b.Dy())
+			continue
+		}
+		if got.At(tc.x, tc.y) != color.Color(mark) {
+			t.Errorf("orientation %d: expected the corner at (%d, %d)", tc.orientation, tc.x, tc.y)
+		}
+	}
+}
@@ -0,0 +1,255 @@
+package media
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"net/http"
+)
+
+const (
+	// MaxPixels bounds width*height before decoding so a tiny file cannot
+	// expand into gigabytes of pixels.
+	MaxPixels = 40_000_000
+	// MaxGIFFrames bounds how many frames an animation may have. Together
+	// with MaxPixels, which applies to all frames at once, it keeps decoding
+	// a GIF as cheap as decoding a still image.
+	MaxGIFFrames     = 500
+	ThumbnailSize    = 320
+	jpegQuality      = 90
+	thumbnailQuality = 80
+)
+
+var ErrUnsupportedType = errors.New("unsupported image type")
+
+// Image is an upload after processing. Data and Thumbnail are re-encoded
+// from decoded pixels, so EXIF and other metadata never survive; a JPEG's
+// orientation is applied to the pixels first, and Width and Height are those
+// of the upright image.
+type Image struct {
+	ContentType          string
+	Data                 []byte
+	Thumbnail            []byte
+	ThumbnailContentType string
+	Width                int
+	Height               int
+}
+
+// SniffContentType reports the MIME type of data from its leading bytes,
+// ignoring whatever the client claimed.
+func SniffContentType(data []byte) string {
+	return http.DetectContentType(data)
+}
+
+// Process validates and re-encodes an uploaded image and renders a thumbnail
+// that fits in ThumbnailSize x ThumbnailSize.
+func Process(data []byte) (Image, error) {
+	contentType := SniffContentType(data)
+	switch contentType {
+	case "image/jpeg", "image/png", "image/gif":
+	default:
+		return Image{}, fmt.Errorf("%w: %s", ErrUnsupportedType, contentType)
+	}
+
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return Image{}, err
+	}
+	if cfg.Width <= 0 || cfg.Height <= 0 || cfg.Width*cfg.Height > MaxPixels {
+		return Image{}, fmt.Errorf("image dimensions %dx%d are not allowed", cfg.Width, cfg.Height)
+	}
+
+	img := Image{
+		ContentType: contentType,
+		Width:       cfg.Width,
+		Height:      cfg.Height,
+	}
+
+	var first image.Image
+	var out bytes.Buffer
+	switch contentType {
+	case "image/jpeg":
+		decoded, err := jpeg.Decode(bytes.NewReader(data))
+		if err != nil {
+			return Image{}, err
+		}
+		decoded = orient(decoded, jpegOrientation(data))
+		img.Width, img.Height = decoded.Bounds().Dx(), decoded.Bounds().Dy()
+		err = jpeg.Encode(&out, decoded, &jpeg.Options{Quality: jpegQuality})
+		if err != nil {
+			return Image{}, err
+		}
+		first = decoded
+	case "image/png":
+		decoded, err := png.Decode(bytes.NewReader(data))
+		if err != nil {
+			return Image{}, err
+		}
+		err = png.Encode(&out, decoded)
+		if err != nil {
+			return Image{}, err
+		}
+		first = decoded
+	case "image/gif":
+		frames := countGIFFrames(data)
+		if frames > MaxGIFFrames || frames*cfg.Width*cfg.Height > MaxPixels {
+			return Image{}, fmt.Errorf("animations of %d %dx%d frames are not allowed", frames, cfg.Width, cfg.Height)
+		}
+
+		// keep every frame so animations still play
+		decoded, err := gif.DecodeAll(bytes.NewReader(data))
+		if err != nil {
+			return Image{}, err
+		}
+		err = gif.EncodeAll(&out, &gif.GIF{
+			Image:     decoded.Image,
+			Delay:     decoded.Delay,
+			LoopCount: decoded.LoopCount,
+			Disposal:  decoded.Disposal,
+			Config:    decoded.Config,
+		})
+		if err != nil {
+			return Image{}, err
+		}
+		first = decoded.Image[0]
+	}
+	img.Data = out.Bytes()
+
+	var thumb bytes.Buffer
+	scaled := Thumbnail(first, ThumbnailSize)
+	if contentType == "image/jpeg" {
+		err = jpeg.Encode(&thumb, scaled, &jpeg.Options{Quality: thumbnailQuality})
+		img.ThumbnailContentType = "image/jpeg"
+	} else {
+		err = png.Encode(&thumb, scaled)
+		img.ThumbnailContentType = "image/png"
+	}
+	if err != nil {
+		return Image{}, err
+	}
+	img.Thumbnail = thumb.Bytes()
+
+	return img, nil
+}
+
+// countGIFFrames counts the image descriptors in a GIF by walking its block
+// structure, without decompressing anything. Malformed data is left for the
+// decoder to reject; the count covers whatever came before it.
+func countGIFFrames(data []byte) int {
+	// header and logical screen descriptor
+	pos := 13
+	if len(data) < pos {
+		return 0
+	}
+	if data[10]&0x80 != 0 {
+		pos += 3 << ((data[10] & 0x07) + 1)
+	}
+
+	// skipSubBlocks moves pos past a run of data sub-blocks
+	skipSubBlocks := func() {
+		for pos < len(data) {
+			n := int(data[pos])
+			pos++
+			if n == 0 {
+				return
+			}
+			pos += n
+		}
+	}
+
+	frames := 0
+	for pos < len(data) {
+		switch data[pos] {
+		case 0x21: // extension: label, then sub-blocks
+			pos += 2
+			skipSubBlocks()
+		case 0x2C: // image descriptor, local color table, LZW code size
+			if pos+10 > len(data) {
+				return frames
+			}
+			frames++
+			flags := data[pos+9]
+			pos += 10
+			if flags&0x80 != 0 {
+				pos += 3 << ((flags & 0x07) + 1)
+			}
+			pos++
+			skipSubBlocks()
+		default: // trailer, or something the decoder will reject
+			return frames
+		}
+	}
+
+	return frames
+}
+
+// Thumbnail downscales src to fit within size x size by averaging the source
+// pixels that fall into each destination pixel. Images that already fit are
+// copied unchanged.
+func Thumbnail(src image.Image, size int) *image.RGBA {
+	b := src.Bounds()
+	w, h := b.Dx(), b.Dy()
+
+	tw, th := w, h
+	if w > size || h > size {
+		if w >= h {
+			tw, th = size, h*size/w
+		} else {
+			tw, th = w*size/h, size
+		}
+	}
+	if tw < 1 {
+		tw = 1
+	}
+	if th < 1 {
+		th = 1
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, tw, th))
+	if tw == w && th == h {
+		draw.Draw(dst, dst.Bounds(), src, b.Min, draw.Src)
+		return dst
+	}
+
+	for y := 0; y < th; y++ {
+		y0 := b.Min.Y + y*h/th
+		y1 := b.Min.Y + (y+1)*h/th
+		if y1 == y0 {
+			y1 = y0 + 1
+		}
+		for x := 0; x < tw; x++ {
+			x0 := b.Min.X + x*w/tw
+			x1 := b.Min.X + (x+1)*w/tw
+			if x1 == x0 {
+				x1 = x0 + 1
+			}
+
+			var r, g, bl, a, n uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					pr, pg, pb, pa := src.At(sx, sy).RGBA()
+					r += uint64(pr)
+					g += uint64(pg)
+					bl += uint64(pb)
+					a += uint64(pa)
+					n++
+				}
+			}
+
+			dst.Set(x, y, color.RGBA64{
+				R: uint16(r / n),
+				G: uint16(g / n),
+				B: uint16(bl / n),
+				A: uint16(a / n),
+			})
+		}
+	}
+
+	return dst
+}
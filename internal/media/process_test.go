@@ -0,0 +1,206 @@
+package media
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"image/color/palette"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"testing"
+)
+
+func testImage(w, h int) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			img.Set(x, y, color.RGBA{R: uint8(x), G: uint8(y), B: 128, A: 255})
+		}
+	}
+	return img
+}
+
+// withExif splices an APP1 Exif segment right after the JPEG SOI marker.
+func withExif(t *testing.T, jpg []byte) []byte {
+	t.Helper()
+	payload := []byte("Exif\x00\x00GPS-SECRET")
+	segment := []byte{0xFF, 0xE1, byte((len(payload) + 2) >> 8), byte(len(payload) + 2)}
+	segment = append(segment, payload...)
+
+	out := append([]byte{}, jpg[:2]...)
+	out = append(out, segment...)
+	return append(out, jpg[2:]...)
+}
+
+func TestProcessJPEGStripsExif(t *testing.T) {
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, testImage(800, 400), nil); err != nil {
+		t.Fatalf("could not encode fixture: %v", err)
+	}
+	upload := withExif(t, buf.Bytes())
+	if !bytes.Contains(upload, []byte("GPS-SECRET")) {
+		t.Fatalf("fixture is missing its Exif segment")
+	}
+
+	img, err := Process(upload)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if img.ContentType != "image/jpeg" || img.Width != 800 || img.Height != 400 {
+		t.Fatalf("unexpected metadata: %s %dx%d", img.ContentType, img.Width, img.Height)
+	}
+
+	if bytes.Contains(img.Data, []byte("GPS-SECRET")) {
+		t.Fatalf("Exif data survived processing")
+	}
+
+	thumb, err := jpeg.Decode(bytes.NewReader(img.Thumbnail))
+	if err != nil {
+		t.Fatalf("thumbnail is not a JPEG: %v", err)
+	}
+	if b := thumb.Bounds(); b.Dx() != ThumbnailSize || b.Dy() != ThumbnailSize/2 {
+		t.Fatalf("unexpected thumbnail size %dx%d", b.Dx(), b.Dy())
+	}
+}
+
+// withOrientation splices an APP1 Exif segment holding only the Orientation
+// tag, big-endian as cameras commonly write it, after the JPEG SOI marker.
+func withOrientation(t *testing.T, jpg []byte, orientation byte) []byte {
+	t.Helper()
+	payload := []byte("Exif\x00\x00MM\x00\x2a\x00\x00\x00\x08" +
+		"\x00\x01" + // one IFD entry
+		"\x01\x12\x00\x03\x00\x00\x00\x01\x00" + string(rune(orientation)) + "\x00\x00" +
+		"\x00\x00\x00\x00") // no next IFD
+	segment := []byte{0xFF, 0xE1, byte((len(payload) + 2) >> 8), byte(len(payload) + 2)}
+	segment = append(segment, payload...)
+
+	out := append([]byte{}, jpg[:2]...)
+	out = append(out, segment...)
+	return append(out, jpg[2:]...)
+}
+
+func TestProcessJPEGAppliesOrientation(t *testing.T) {
+	// a landscape frame whose left half is the top of the scene: a phone
+	// held upright stores it like this with orientation 6
+	src := image.NewRGBA(image.Rect(0, 0, 800, 400))
+	for y := 0; y < 400; y++ {
+		for x := 0; x < 800; x++ {
+			c := color.RGBA{R: 255, A: 255}
+			if x >= 400 {
+				c = color.RGBA{B: 255, A: 255}
+			}
+			src.Set(x, y, c)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, src, nil); err != nil {
+		t.Fatalf("could not encode fixture: %v", err)
+	}
+
+	img, err := Process(withOrientation(t, buf.Bytes(), 6))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if img.Width != 400 || img.Height != 800 {
+		t.Fatalf("expected an upright 400x800 image, got %dx%d", img.Width, img.Height)
+	}
+
+	for name, data := range map[string][]byte{"image": img.Data, "thumbnail": img.Thumbnail} {
+		decoded, err := jpeg.Decode(bytes.NewReader(data))
+		if err != nil {
+			t.Fatalf("%s is not a JPEG: %v", name, err)
+		}
+
+		b := decoded.Bounds()
+		if b.Dx()*2 != b.Dy() {
+			t.Errorf("expected the %s to be portrait, got %dx%d", name, b.Dx(), b.Dy())
+		}
+		if r, _, bl, _ := decoded.At(b.Dx()/2, b.Dy()/4).RGBA(); r < bl {
+			t.Errorf("expected the top of the %s to be red", name)
+		}
+		if r, _, bl, _ := decoded.At(b.Dx()/2, b.Dy()*3/4).RGBA(); bl < r {
+			t.Errorf("expected the bottom of the %s to be blue", name)
+		}
+	}
+}
+
+func TestProcessPNG(t *testing.T) {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, testImage(10, 20)); err != nil {
+		t.Fatalf("could not encode fixture: %v", err)
+	}
+
+	img, err := Process(buf.Bytes())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if img.ThumbnailContentType != "image/png" {
+		t.Fatalf("expected a PNG thumbnail, got %s", img.ThumbnailContentType)
+	}
+
+	thumb, err := png.Decode(bytes.NewReader(img.Thumbnail))
+	if err != nil {
+		t.Fatalf("thumbnail is not a PNG: %v", err)
+	}
+	if b := thumb.Bounds(); b.Dx() != 10 || b.Dy() != 20 {
+		t.Fatalf("small images should not be resized, got %dx%d", b.Dx(), b.Dy())
+	}
+}
+
+func TestProcessRejectsNonImages(t *testing.T) {
+	_, err := Process([]byte("<html><script>alert(1)</script></html>"))
+	if !errors.Is(err, ErrUnsupportedType) {
+		t.Fatalf("expected ErrUnsupportedType, got %v", err)
+	}
+}
+
+func testGIF(t *testing.T, frames int) []byte {
+	t.Helper()
+	anim := &gif.GIF{}
+	for i := 0; i < frames; i++ {
+		anim.Image = append(anim.Image, image.NewPaletted(image.Rect(0, 0, 4, 4), palette.Plan9))
+		anim.Delay = append(anim.Delay, 10)
+	}
+
+	var buf bytes.Buffer
+	if err := gif.EncodeAll(&buf, anim); err != nil {
+		t.Fatalf("could not encode fixture: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestCountGIFFrames(t *testing.T) {
+	for _, frames := range []int{1, 3, 40} {
+		if got := countGIFFrames(testGIF(t, frames)); got != frames {
+			t.Errorf("expected %d frames, got %d", frames, got)
+		}
+	}
+
+	if got := countGIFFrames([]byte("GIF89a")); got != 0 {
+		t.Errorf("expected a truncated GIF to have no frames, got %d", got)
+	}
+}
+
+func TestProcessGIF(t *testing.T) {
+	img, err := Process(testGIF(t, 3))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	decoded, err := gif.DecodeAll(bytes.NewReader(img.Data))
+	if err != nil {
+		t.Fatalf("output is not a GIF: %v", err)
+	}
+	if len(decoded.Image) != 3 {
+		t.Fatalf("expected every frame to survive, got %d", len(decoded.Image))
+	}
+
+	if _, err := Process(testGIF(t, MaxGIFFrames+1)); err == nil {
+		t.Fatalf("expected a GIF with more than %d frames to be rejected", MaxGIFFrames)
+	}
+}
@@ -0,0 +1,88 @@
+package moderation
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Action is what a rule does to a chirp that matches it.
+type Action int
+
+const (
+	// Mask replaces the matched text with asterisks and keeps the chirp.
+	Mask Action = iota
+	// Flag keeps the chirp unchanged but records it for review.
+	Flag
+	// Reject refuses the chirp.
+	Reject
+)
+
+func (a Action) String() string {
+	switch a {
+	case Mask:
+		return "mask"
+	case Flag:
+		return "flag"
+	case Reject:
+		return "reject"
+	}
+	return fmt.Sprintf("Action(%d)", int(a))
+}
+
+// ParseAction is the inverse of Action.String.
+func ParseAction(s string) (Action, error) {
+	switch strings.ToLower(s) {
+	case "mask":
+		return Mask, nil
+	case "flag":
+		return Flag, nil
+	case "reject":
+		return Reject, nil
+	}
+	return 0, fmt.Errorf("unknown moderation action %q", s)
+}
+
+// Result is the outcome of running a chirp through a Filter. Body holds the
+// chirp as it should be stored, with masked text already replaced.
+type Result struct {
+	Body     string
+	Flagged  bool
+	Rejected bool
+	Reasons  []string
+}
+
+func (r *Result) apply(action Action, reason string) {
+	switch action {
+	case Flag:
+		r.Flagged = true
+	case Reject:
+		r.Rejected = true
+	default:
+		return
+	}
+	r.Reasons = append(r.Reasons, reason)
+}
+
+// Filter inspects a chirp and records masks, flags and rejections on res.
+type Filter interface {
+	Apply(res *Result)
+}
+
+// Chain runs each filter in order. Later filters see the body as masked by
+// earlier ones.
+type Chain []Filter
+
+func (c Chain) Apply(res *Result) {
+	for _, f := range c {
+		f.Apply(res)
+	}
+}
+
+// Run is a convenience for applying f to a fresh Result for body.
+func Run(f Filter, body string) Result {
+	res := Result{Body: body}
+	f.Apply(&res)
+	return res
+}
+
+const maskText = "****"
@@ -0,0 +1,138 @@
+package moderation
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func writeWordList(t *testing.T, contents string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "words.txt")
+	if err := os.WriteFile(path, []byte(contents), 0o644); err != nil {
+		t.Fatalf("could not write word list: %v", err)
+	}
+	return path
+}
+
+func TestTokenize(t *testing.T) {
+	body := "Kerfuffle!\nnaïve café—ok"
+	tokens := Tokenize(body)
+
+	var words []string
+	for _, token := range tokens {
+		words = append(words, token.Text)
+		if got := strings.ToLower(body[token.Start:token.End]); got != token.Text {
+			t.Fatalf("token %q has offsets covering %q", token.Text, got)
+		}
+	}
+
+	expected := []string{"kerfuffle", "naïve", "café", "ok"}
+	if !reflect.DeepEqual(words, expected) {
+		t.Fatalf("expected %v, got %v", expected, words)
+	}
+}
+
+func TestChain(t *testing.T) {
+	wl, err := NewWordList(writeWordList(t, `
+# default action is mask
+kerfuffle
+Sharbert
+flag borderline
+reject spamword
+`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	chain := Chain{
+		wl,
+		RegexFilter{
+			{Pattern: regexp.MustCompile(`(?i)buy\s+followers`), Action: Reject, Reason: "spam"},
+			{Pattern: regexp.MustCompile(`\d{3}-\d{4}`), Action: Mask},
+		},
+	}
+
+	tests := []struct {
+		name     string
+		body     string
+		expected Result
+	}{
+		{
+			name:     "clean",
+			body:     "hello world",
+			expected: Result{Body: "hello world"},
+		},
+		{
+			name:     "mask with punctuation and newlines",
+			body:     "What a Kerfuffle!\nsharbert, really",
+			expected: Result{Body: "What a ****!\n****, really"},
+		},
+		{
+			name:     "substrings are not masked",
+			body:     "kerfuffles",
+			expected: Result{Body: "kerfuffles"},
+		},
+		{
+			name:     "flag",
+			body:     "a borderline take",
+			expected: Result{Body: "a borderline take", Flagged: true, Reasons: []string{`contains "borderline"`}},
+		},
+		{
+			name:     "reject from word list and regex",
+			body:     "SpamWord: Buy  followers now",
+			expected: Result{Body: "SpamWord: Buy  followers now", Rejected: true, Reasons: []string{`contains "spamword"`, "spam"}},
+		},
+		{
+			name:     "regex mask",
+			body:     "call 555-1234",
+			expected: Result{Body: "call ****"},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got := Run(chain, tc.body)
+			if !reflect.DeepEqual(got, tc.expected) {
+				t.Fatalf("expected %+v, got %+v", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestWordListReload(t *testing.T) {
+	path := writeWordList(t, "fornax\n")
+	wl, err := NewWordList(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if got := Run(wl, "fornax").Body; got != "****" {
+		t.Fatalf("expected fornax to be masked, got %q", got)
+	}
+
+	if err := os.WriteFile(path, []byte("reject fornax\n"), 0o644); err != nil {
+		t.Fatalf("could not rewrite word list: %v", err)
+	}
+	if err := wl.Reload(); err != nil {
+		t.Fatalf("unexpected reload error: %v", err)
+	}
+
+	if got := Run(wl, "fornax"); !got.Rejected {
+		t.Fatalf("expected fornax to be rejected after reload, got %+v", got)
+	}
+
+	if err := os.WriteFile(path, []byte("explode fornax\n"), 0o644); err != nil {
+		t.Fatalf("could not rewrite word list: %v", err)
+	}
+	if err := wl.Reload(); err == nil {
+		t.Fatalf("expected reload of an invalid list to fail")
+	}
+
+	if got := Run(wl, "fornax"); !got.Rejected {
+		t.Fatalf("expected the previous list to stay active, got %+v", got)
+	}
+}
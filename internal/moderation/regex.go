@@ -0,0 +1,31 @@
+package moderation
+
+import (
+	"regexp"
+)
+
+// RegexRule applies Action to every match of Pattern. Reason is reported
+// back to the author for rejected chirps and stored for flagged ones.
+type RegexRule struct {
+	Pattern *regexp.Regexp
+	Action  Action
+	Reason  string
+}
+
+// RegexFilter applies a fixed set of regex rules in order.
+type RegexFilter []RegexRule
+
+func (rf RegexFilter) Apply(res *Result) {
+	for _, rule := range rf {
+		if !rule.Pattern.MatchString(res.Body) {
+			continue
+		}
+
+		if rule.Action == Mask {
+			res.Body = rule.Pattern.ReplaceAllLiteralString(res.Body, maskText)
+			continue
+		}
+
+		res.apply(rule.Action, rule.Reason)
+	}
+}
@@ -0,0 +1,53 @@
+package moderation
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Token is a word in a chirp body. Start and End are byte offsets into the
+// body, so a token can be replaced in place.
+type Token struct {
+	Text  string
+	Start int
+	End   int
+}
+
+// Tokenize splits body into words made of letters, digits and combining
+// marks. Any other rune (spaces, newlines, punctuation, emoji) separates
+// words, so "Kerfuffle!" and "\nkerfuffle" both yield "kerfuffle".
+func Tokenize(body string) []Token {
+	var tokens []Token
+
+	start := -1
+	for i, r := range body {
+		if isWordRune(r) {
+			if start < 0 {
+				start = i
+			}
+			continue
+		}
+		if start >= 0 {
+			tokens = append(tokens, newToken(body, start, i))
+			start = -1
+		}
+	}
+	if start >= 0 {
+		tokens = append(tokens, newToken(body, start, len(body)))
+	}
+
+	return tokens
+}
+
+func isWordRune(r rune) bool {
+	return r != utf8.RuneError && (unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.Is(unicode.Mn, r))
+}
+
+func newToken(body string, start, end int) Token {
+	return Token{
+		Text:  strings.ToLower(body[start:end]),
+		Start: start,
+		End:   end,
+	}
+}
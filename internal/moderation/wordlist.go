@@ -0,0 +1,130 @@
+package moderation
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+)
+
+// WordList matches whole words against a list loaded from a file. Each
+// non-empty line holds a word, optionally preceded by an action:
+//
+//	# comment
+//	kerfuffle
+//	reject spamword
+//	flag borderline
+//
+// Words without an action are masked. The list can be reloaded at runtime.
+type WordList struct {
+	path string
+
+	mu    sync.RWMutex
+	words map[string]Action
+}
+
+// NewWordList loads the word list at path.
+func NewWordList(path string) (*WordList, error) {
+	wl := &WordList{path: path}
+	if err := wl.Reload(); err != nil {
+		return nil, err
+	}
+	return wl, nil
+}
+
+// Reload re-reads the word list from disk. On error the previous list stays
+// in effect.
+func (wl *WordList) Reload() error {
+	f, err := os.Open(wl.path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	words, err := parseWordList(f)
+	if err != nil {
+		return fmt.Errorf("%s: %w", wl.path, err)
+	}
+
+	wl.mu.Lock()
+	wl.words = words
+	wl.mu.Unlock()
+
+	return nil
+}
+
+// Len reports how many words are loaded.
+func (wl *WordList) Len() int {
+	wl.mu.RLock()
+	defer wl.mu.RUnlock()
+	return len(wl.words)
+}
+
+func (wl *WordList) Apply(res *Result) {
+	wl.mu.RLock()
+	words := wl.words
+	wl.mu.RUnlock()
+
+	var masked strings.Builder
+	last := 0
+	for _, token := range Tokenize(res.Body) {
+		action, ok := words[token.Text]
+		if !ok {
+			continue
+		}
+
+		if action == Mask {
+			masked.WriteString(res.Body[last:token.Start])
+			masked.WriteString(maskText)
+			last = token.End
+			continue
+		}
+
+		res.apply(action, fmt.Sprintf("contains %q", token.Text))
+	}
+
+	if last > 0 {
+		masked.WriteString(res.Body[last:])
+		res.Body = masked.String()
+	}
+}
+
+func parseWordList(r io.Reader) (map[string]Action, error) {
+	words := map[string]Action{}
+
+	scanner := bufio.NewScanner(r)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.Fields(line)
+		action := Mask
+		word := fields[0]
+		switch len(fields) {
+		case 1:
+		case 2:
+			parsed, err := ParseAction(fields[0])
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", lineNo, err)
+			}
+			action = parsed
+			word = fields[1]
+		default:
+			return nil, fmt.Errorf("line %d: expected \"[action] word\"", lineNo)
+		}
+
+		words[strings.ToLower(word)] = action
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return words, nil
+}
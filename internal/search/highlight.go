@@ -0,0 +1,52 @@
+package search
+
+import (
+	"html"
+	"strings"
+	"unicode/utf8"
+)
+
+// ts_headline wraps matched terms in these private-use characters rather
+// than in markup, so the chirp text can be escaped before the markup goes
+// in. The SearchChirps query must use the same code points, U+E000 and
+// U+E001.
+const (
+	highlightStart = '\uE000'
+	highlightStop  = '\uE001'
+)
+
+// HighlightHTML turns a ts_headline result into HTML: the text is escaped
+// and every match is wrapped in <mark>. Stray delimiters typed into a chirp
+// can only ever open or close a <mark>, and the tags always balance.
+func HighlightHTML(headline string) string {
+	var b strings.Builder
+	open := false
+
+	for {
+		i := strings.IndexFunc(headline, func(r rune) bool {
+			return r == highlightStart || r == highlightStop
+		})
+		if i < 0 {
+			break
+		}
+
+		b.WriteString(html.EscapeString(headline[:i]))
+
+		r, size := utf8.DecodeRuneInString(headline[i:])
+		if r == highlightStart && !open {
+			b.WriteString("<mark>")
+			open = true
+		} else if r == highlightStop && open {
+			b.WriteString("</mark>")
+			open = false
+		}
+		headline = headline[i+size:]
+	}
+
+	b.WriteString(html.EscapeString(headline))
+	if open {
+		b.WriteString("</mark>")
+	}
+
+	return b.String()
+}
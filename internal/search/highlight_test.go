@@ -0,0 +1,24 @@
+package search
+
+import "testing"
+
+func TestHighlightHTML(t *testing.T) {
+	tests := []struct {
+		headline string
+		expected string
+	}{
+		{"no matches", "no matches"},
+		{"a \uE000cat\uE001 sat", "a <mark>cat</mark> sat"},
+		{"\uE000one\uE001 and \uE000two\uE001", "<mark>one</mark> and <mark>two</mark>"},
+		{"<script>alert(\"\uE000hi\uE001\")</script>", "&lt;script&gt;alert(&#34;<mark>hi</mark>&#34;)&lt;/script&gt;"},
+		{"<mark>fake</mark>", "&lt;mark&gt;fake&lt;/mark&gt;"},
+		{"\uE001stray \uE000open", "stray <mark>open</mark>"},
+		{"\uE000twice\uE000 over\uE001\uE001", "<mark>twice over</mark>"},
+	}
+
+	for _, tc := range tests {
+		if got := HighlightHTML(tc.headline); got != tc.expected {
+			t.Errorf("HighlightHTML(%q): expected %q, got %q", tc.headline, tc.expected, got)
+		}
+	}
+}
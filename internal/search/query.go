@@ -0,0 +1,79 @@
+package search
+
+import (
+	"errors"
+	"strings"
+	"unicode"
+)
+
+// ToTSQuery converts a user search string into a Postgres to_tsquery
+// expression. Bare words are ANDed together, "quoted text" becomes a phrase
+// match and a trailing * turns a word into a prefix match. Everything other
+// than letters and digits is dropped, so the output never contains tsquery
+// operators the user typed themselves.
+func ToTSQuery(input string) (string, error) {
+	var terms []string
+
+	for i, segment := range strings.Split(input, `"`) {
+		// odd segments sit between a pair of quotes
+		if i%2 == 1 {
+			if phrase := phraseTerm(segment); phrase != "" {
+				terms = append(terms, phrase)
+			}
+			continue
+		}
+
+		for _, word := range strings.Fields(segment) {
+			if term := wordTerm(word); term != "" {
+				terms = append(terms, term)
+			}
+		}
+	}
+
+	if len(terms) == 0 {
+		return "", errors.New("search query is empty")
+	}
+
+	return strings.Join(terms, " & "), nil
+}
+
+func phraseTerm(phrase string) string {
+	var lexemes []string
+	for _, word := range strings.Fields(phrase) {
+		lexemes = append(lexemes, lexemesOf(word)...)
+	}
+
+	switch len(lexemes) {
+	case 0:
+		return ""
+	case 1:
+		return lexemes[0]
+	}
+	return "(" + strings.Join(lexemes, " <-> ") + ")"
+}
+
+// wordTerm handles a single unquoted word. Words with inner punctuation such
+// as "e-mail" are matched as a phrase of their parts.
+func wordTerm(word string) string {
+	prefix := strings.HasSuffix(word, "*")
+
+	lexemes := lexemesOf(word)
+	if len(lexemes) == 0 {
+		return ""
+	}
+
+	if prefix {
+		lexemes[len(lexemes)-1] += ":*"
+	}
+
+	if len(lexemes) == 1 {
+		return lexemes[0]
+	}
+	return "(" + strings.Join(lexemes, " <-> ") + ")"
+}
+
+func lexemesOf(word string) []string {
+	return strings.FieldsFunc(strings.ToLower(word), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
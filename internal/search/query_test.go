@@ -0,0 +1,79 @@
+package search
+
+import "testing"
+
+func TestToTSQuery(t *testing.T) {
+	tests := []struct {
+		name      string
+		input     string
+		expected  string
+		expectErr bool
+	}{
+		{
+			name:     "single word",
+			input:    "chirpy",
+			expected: "chirpy",
+		},
+		{
+			name:     "words are ANDed",
+			input:    "hello   World",
+			expected: "hello & world",
+		},
+		{
+			name:     "prefix",
+			input:    "chirp*",
+			expected: "chirp:*",
+		},
+		{
+			name:     "phrase",
+			input:    `"boot dev" rocks`,
+			expected: "(boot <-> dev) & rocks",
+		},
+		{
+			name:     "inner punctuation",
+			input:    "e-mail",
+			expected: "(e <-> mail)",
+		},
+		{
+			name:     "operators are stripped",
+			input:    "foo & !bar | (baz:*)",
+			expected: "foo & bar & baz",
+		},
+		{
+			name:     "unterminated quote",
+			input:    `"open phrase`,
+			expected: "(open <-> phrase)",
+		},
+		{
+			name:     "unicode",
+			input:    "Café",
+			expected: "café",
+		},
+		{
+			name:      "empty",
+			input:     `  "" * `,
+			expectErr: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := ToTSQuery(tc.input)
+
+			if tc.expectErr {
+				if err == nil {
+					t.Fatalf("expected error but got none")
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if got != tc.expected {
+				t.Fatalf("expected %q, got %q", tc.expected, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,45 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestLocal(t *testing.T) {
+	ctx := context.Background()
+	store, err := NewLocal(t.TempDir())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if err := store.Put(ctx, "ab/blob.png", strings.NewReader("data")); err != nil {
+		t.Fatalf("Put failed: %v", err)
+	}
+
+	rc, err := store.Open(ctx, "ab/blob.png")
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	got, _ := io.ReadAll(rc)
+	rc.Close()
+	if string(got) != "data" {
+		t.Fatalf("expected %q, got %q", "data", got)
+	}
+
+	if err := store.Delete(ctx, "ab/blob.png"); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+
+	if _, err := store.Open(ctx, "ab/blob.png"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+
+	for _, key := range []string{"", "../escape", "/abs", `a\b`} {
+		if err := store.Put(ctx, key, strings.NewReader("x")); err == nil {
+			t.Fatalf("expected key %q to be rejected", key)
+		}
+	}
+}
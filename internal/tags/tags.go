@@ -0,0 +1,55 @@
+package tags
+
+import (
+	"regexp"
+	"strings"
+)
+
+var (
+	hashtagRegex = regexp.MustCompile(`(?:^|[^\p{L}\p{N}_&#])#([\p{L}\p{N}_]+)`)
+	// the trailing group catches the @ of an email address, which is not a
+	// mention of its local part
+	mentionRegex = regexp.MustCompile(`(?:^|[^\p{L}\p{N}_@])@([A-Za-z0-9_]+)(@?)`)
+	handleRegex  = regexp.MustCompile(`^[A-Za-z0-9_]{3,30}$`)
+)
+
+// Hashtags returns the distinct hashtags in body, lowercased and without the
+// leading #, in the order they first appear.
+func Hashtags(body string) []string {
+	return extract(hashtagRegex.FindAllStringSubmatch(body, -1))
+}
+
+// Mentions returns the distinct handles mentioned as @handle in body,
+// lowercased and without the leading @, in the order they first appear.
+func Mentions(body string) []string {
+	matches := [][]string{}
+	for _, match := range mentionRegex.FindAllStringSubmatch(body, -1) {
+		if match[2] == "" {
+			matches = append(matches, match)
+		}
+	}
+
+	return extract(matches)
+}
+
+// IsHandle reports whether handle can be mentioned: 3 to 30 ASCII letters,
+// digits or underscores.
+func IsHandle(handle string) bool {
+	return handleRegex.MatchString(handle)
+}
+
+func extract(matches [][]string) []string {
+	seen := map[string]bool{}
+	found := []string{}
+
+	for _, match := range matches {
+		value := strings.ToLower(strings.TrimRight(match[1], "."))
+		if value == "" || seen[value] {
+			continue
+		}
+		seen[value] = true
+		found = append(found, value)
+	}
+
+	return found
+}
@@ -0,0 +1,105 @@
+package tags
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestHashtags(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     string
+		expected []string
+	}{
+		{
+			name:     "none",
+			body:     "just a chirp",
+			expected: []string{},
+		},
+		{
+			name:     "lowercased and deduplicated",
+			body:     "#Go is great. #go\n#golang!",
+			expected: []string{"go", "golang"},
+		},
+		{
+			name:     "unicode",
+			body:     "café #crème_brûlée",
+			expected: []string{"crème_brûlée"},
+		},
+		{
+			name:     "ignores anchors and entities",
+			body:     "see page#section or &#39; or ##double",
+			expected: []string{},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got := Hashtags(tc.body)
+			if !reflect.DeepEqual(got, tc.expected) {
+				t.Fatalf("expected %v, got %v", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestMentions(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     string
+		expected []string
+	}{
+		{
+			name:     "none",
+			body:     "email me at bob@example.com",
+			expected: []string{},
+		},
+		{
+			name:     "single mention",
+			body:     "hi @Alice_99.",
+			expected: []string{"alice_99"},
+		},
+		{
+			name:     "multiple mentions",
+			body:     "@ann and (@bob) and @ann again",
+			expected: []string{"ann", "bob"},
+		},
+		{
+			name:     "ignores email addresses",
+			body:     "ask @carol@example.com or dave@example.com, @erin",
+			expected: []string{"erin"},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got := Mentions(tc.body)
+			if !reflect.DeepEqual(got, tc.expected) {
+				t.Fatalf("expected %v, got %v", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestIsHandle(t *testing.T) {
+	tests := []struct {
+		handle   string
+		expected bool
+	}{
+		{handle: "walt", expected: true},
+		{handle: "Walt_White_1958", expected: true},
+		{handle: "wa", expected: false},
+		{handle: strings.Repeat("w", 31), expected: false},
+		{handle: "walt.white", expected: false},
+		{handle: "wält", expected: false},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.handle, func(t *testing.T) {
+			if got := IsHandle(tc.handle); got != tc.expected {
+				t.Fatalf("expected %v, got %v", tc.expected, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,88 @@
+// Package webhook signs, verifies and sends webhook payloads. A signature
+// header looks like
+//
+//	t=1700000000,v1=5257a869e7ecebeda32affa62cdca3fa51cad7e77a0e56ff536d0ce8e108d8bd
+//
+// where v1 is the hex HMAC-SHA256 of "<t>.<body>". Binding the timestamp
+// into the MAC lets receivers reject old deliveries that are replayed.
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	ErrMalformedSignature = errors.New("malformed signature header")
+	ErrSignatureMismatch  = errors.New("signature does not match")
+	ErrSignatureExpired   = errors.New("signature timestamp is outside the tolerance")
+)
+
+func mac(secret []byte, timestamp int64, body []byte) []byte {
+	m := hmac.New(sha256.New, secret)
+	fmt.Fprintf(m, "%d.", timestamp)
+	m.Write(body)
+	return m.Sum(nil)
+}
+
+// Sign returns the signature header for body sent at t.
+func Sign(secret []byte, t time.Time, body []byte) string {
+	ts := t.Unix()
+	return fmt.Sprintf("t=%d,v1=%s", ts, hex.EncodeToString(mac(secret, ts, body)))
+}
+
+// Verify checks header against body. The timestamp must be within tolerance
+// of now either way. A header may carry several v1 values, so a sender can
+// sign with an old and a new secret while rotating; any match is enough.
+func Verify(secret []byte, header string, body []byte, now time.Time, tolerance time.Duration) error {
+	var (
+		ts         int64
+		haveTS     bool
+		signatures [][]byte
+	)
+
+	for _, part := range strings.Split(header, ",") {
+		key, value, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			return ErrMalformedSignature
+		}
+
+		switch key {
+		case "t":
+			parsed, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				return ErrMalformedSignature
+			}
+			ts, haveTS = parsed, true
+		case "v1":
+			sig, err := hex.DecodeString(value)
+			if err != nil {
+				return ErrMalformedSignature
+			}
+			signatures = append(signatures, sig)
+		}
+	}
+
+	if !haveTS || len(signatures) == 0 {
+		return ErrMalformedSignature
+	}
+
+	if d := now.Sub(time.Unix(ts, 0)); d > tolerance || d < -tolerance {
+		return ErrSignatureExpired
+	}
+
+	want := mac(secret, ts, body)
+	for _, sig := range signatures {
+		if hmac.Equal(sig, want) {
+			return nil
+		}
+	}
+
+	return ErrSignatureMismatch
+}
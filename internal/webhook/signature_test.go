@@ -0,0 +1,57 @@
+package webhook
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestSignAndVerify(t *testing.T) {
+	secret := []byte("whsec_test")
+	body := []byte(`{"id":"evt_1","event":"user.upgraded"}`)
+	now := time.Unix(1700000000, 0)
+
+	header := Sign(secret, now, body)
+
+	if err := Verify(secret, header, body, now.Add(time.Minute), 5*time.Minute); err != nil {
+		t.Fatalf("expected a fresh signature to verify: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		secret []byte
+		header string
+		body   []byte
+		now    time.Time
+		want   error
+	}{
+		{"tampered body", secret, header, []byte(`{"id":"evt_1","event":"user.downgraded"}`), now, ErrSignatureMismatch},
+		{"wrong secret", []byte("other"), header, body, now, ErrSignatureMismatch},
+		{"too old", secret, header, body, now.Add(6 * time.Minute), ErrSignatureExpired},
+		{"from the future", secret, header, body, now.Add(-6 * time.Minute), ErrSignatureExpired},
+		{"no timestamp", secret, "v1=abcd", body, now, ErrMalformedSignature},
+		{"no signature", secret, "t=1700000000", body, now, ErrMalformedSignature},
+		{"not hex", secret, "t=1700000000,v1=zz", body, now, ErrMalformedSignature},
+		{"empty", secret, "", body, now, ErrMalformedSignature},
+	}
+
+	for _, tc := range tests {
+		err := Verify(tc.secret, tc.header, tc.body, tc.now, 5*time.Minute)
+		if !errors.Is(err, tc.want) {
+			t.Errorf("%s: expected %v, got %v", tc.name, tc.want, err)
+		}
+	}
+}
+
+func TestVerifyAcceptsAnyOfSeveralSignatures(t *testing.T) {
+	body := []byte("{}")
+	now := time.Unix(1700000000, 0)
+
+	header := Sign([]byte("old"), now, body) + ",v1=" + Sign([]byte("new"), now, body)[len("t=1700000000,v1="):]
+
+	for _, secret := range []string{"old", "new"} {
+		if err := Verify([]byte(secret), header, body, now, time.Minute); err != nil {
+			t.Errorf("expected the %s secret to verify: %v", secret, err)
+		}
+	}
+}
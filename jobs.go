@@ -0,0 +1,60 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"os"
+	"strconv"
+
+	"github.com/adi290491/chirpy/internal/jobs"
+)
+
+// Job kinds. Payloads are JSON and must stay readable by the next release,
+// since jobs can outlive a deploy.
+const (
+	jobSendEmailToken        = "email.send_token"
+	jobRequestPasswordReset  = "password_reset.request"
+	jobDeleteBlobs           = "storage.delete_blobs"
+	jobPublishScheduledChirp = "chirp.publish_scheduled"
+	jobDeliverWebhook        = "webhook.deliver"
+)
+
+func (c *apiConfig) initJobs() {
+	c.jobs = jobs.NewRunner(c.db)
+
+	if raw := os.Getenv("JOB_WORKERS"); raw != "" {
+		workers, err := strconv.Atoi(raw)
+		if err != nil || workers < 1 {
+			log.Fatalf("JOB_WORKERS must be a positive integer, got %q", raw)
+		}
+		c.jobs.Workers = workers
+	}
+
+	c.jobs.Handle(jobSendEmailToken, c.sendEmailToken)
+	c.jobs.Handle(jobRequestPasswordReset, c.startPasswordReset)
+	c.jobs.Handle(jobDeleteBlobs, c.deleteBlobs)
+	c.jobs.Handle(jobPublishScheduledChirp, c.publishScheduledChirp)
+	c.jobs.Handle(jobDeliverWebhook, c.deliverWebhook)
+}
+
+type deleteBlobsJob struct {
+	Keys []string `json:"keys"`
+}
+
+// deleteBlobs removes stored files whose rows are gone. Deleting a missing
+// file succeeds, so a retry can start from the top.
+func (c *apiConfig) deleteBlobs(ctx context.Context, payload json.RawMessage) error {
+	var job deleteBlobsJob
+	if err := json.Unmarshal(payload, &job); err != nil {
+		return jobs.Permanent(err)
+	}
+
+	for _, key := range job.Keys {
+		if err := c.storage.Delete(ctx, key); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
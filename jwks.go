@@ -0,0 +1,36 @@
+package main
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"os"
+
+	"github.com/adi290491/chirpy/internal/auth"
+)
+
+const defaultKeysDir = "keys"
+
+func (c *apiConfig) initKeyring() {
+	dir := os.Getenv("JWT_KEYS_DIR")
+	if dir == "" {
+		dir = defaultKeysDir
+	}
+
+	keyring, err := auth.LoadKeyring(dir, os.Getenv("JWT_SIGNING_KID"))
+	if errors.Is(err, auth.ErrNoSigningKey) && c.PLATFORM == "dev" {
+		log.Printf("%v; signing with a throwaway key, tokens will not survive a restart", err)
+		keyring, err = auth.GenerateKeyring()
+	}
+	if err != nil {
+		log.Fatalf("could not load JWT signing keys: %v", err)
+	}
+
+	c.keyring = keyring
+}
+
+func (c *apiConfig) GetJWKS(w http.ResponseWriter, r *http.Request) {
+	// verifiers may cache briefly; a new key is published before it signs
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	respondWithJSON(w, http.StatusOK, c.keyring.JWKS())
+}
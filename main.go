@@ -1,29 +1,62 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"log"
 	"net/http"
 	"os"
+	"os/signal"
+	"sync"
 	"sync/atomic"
+	"syscall"
+	"time"
 
 	"github.com/joho/godotenv"
 	_ "github.com/lib/pq"
 )
 
-const (
-	maxChirpLength = 140
-)
+// shutdownTimeout bounds how long open requests get to finish after a
+// SIGINT or SIGTERM.
+const shutdownTimeout = 30 * time.Second
 
 func main() {
 	godotenv.Load()
 
 	apiCfg := &apiConfig{
 		fileServerHits: atomic.Int32{},
-		JWT_SECRET:     os.Getenv("JWT_SECRET"),
 		PLATFORM:       os.Getenv("PLATFORM"),
 		API_KEY:        os.Getenv("POLKA_KEY"),
+		polkaSecret:    os.Getenv("POLKA_WEBHOOK_SECRET"),
+	}
+
+	if len(os.Args) > 1 {
+		os.Exit(apiCfg.runCommand(os.Args[1:]))
 	}
+
 	apiCfg.initDB()
+	apiCfg.initKeyring()
+	apiCfg.initModeration()
+	apiCfg.initStorage()
+	apiCfg.initMailer()
+	apiCfg.initPolka()
+	apiCfg.initWebhooks()
+	apiCfg.initJobs()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	var workers sync.WaitGroup
+	for _, run := range []func(context.Context){
+		apiCfg.jobs.Run,
+		apiCfg.runSubscriptionExpiry,
+	} {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			run(ctx)
+		}()
+	}
 
 	mux := http.NewServeMux()
 
@@ -36,8 +69,24 @@ func main() {
 		Handler: mux,
 	}
 
-	if err := server.ListenAndServe(); err != nil {
-		log.Fatalf("could not start the server: %v", err)
+	go func() {
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatalf("could not start the server: %v", err)
+		}
+	}()
+
+	<-ctx.Done()
+	stop()
+	log.Printf("shutting down")
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		log.Printf("could not finish open requests: %v", err)
 	}
 
+	// jobs already started run to completion; the rest wait in the table
+	workers.Wait()
+
 }
@@ -0,0 +1,242 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"os"
+	"path"
+
+	"github.com/adi290491/chirpy/internal/database"
+	"github.com/adi290491/chirpy/internal/media"
+	"github.com/adi290491/chirpy/internal/storage"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+const (
+	maxUploadSize    = 5 << 20
+	maxMediaPerChirp = 4
+	defaultMediaDir  = "uploads"
+	mediaURLPrefix   = "/media/"
+)
+
+type Media struct {
+	ID           uuid.UUID `json:"id"`
+	ContentType  string    `json:"content_type"`
+	URL          string    `json:"url"`
+	ThumbnailURL string    `json:"thumbnail_url"`
+	Width        int32     `json:"width"`
+	Height       int32     `json:"height"`
+}
+
+func mediaFromDB(row database.Medium) Media {
+	return Media{
+		ID:           row.ID,
+		ContentType:  row.ContentType,
+		URL:          mediaURLPrefix + row.StorageKey,
+		ThumbnailURL: mediaURLPrefix + row.ThumbnailKey,
+		Width:        row.Width,
+		Height:       row.Height,
+	}
+}
+
+func (c *apiConfig) initStorage() {
+	dir := os.Getenv("MEDIA_DIR")
+	if dir == "" {
+		dir = defaultMediaDir
+	}
+
+	store, err := storage.NewLocal(dir)
+	if err != nil {
+		log.Fatalf("could not initialise media storage: %v", err)
+	}
+
+	c.storage = store
+}
+
+func extensionFor(contentType string) string {
+	switch contentType {
+	case "image/jpeg":
+		return ".jpg"
+	case "image/png":
+		return ".png"
+	case "image/gif":
+		return ".gif"
+	}
+	return ""
+}
+
+func (c *apiConfig) UploadMedia(w http.ResponseWriter, r *http.Request) {
+	userID := currentUserID(r)
+
+	// leave room for the multipart framing around the file itself
+	r.Body = http.MaxBytesReader(w, r.Body, maxUploadSize+1<<10)
+
+	file, _, err := r.FormFile("file")
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "expected an image in the \"file\" form field", err)
+		return
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(io.LimitReader(file, maxUploadSize+1))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "error while reading upload", err)
+		return
+	}
+
+	if len(data) > maxUploadSize {
+		respondWithError(w, http.StatusRequestEntityTooLarge, "image is too large", fmt.Errorf("upload exceeds %d bytes", maxUploadSize))
+		return
+	}
+
+	img, err := media.Process(data)
+	if errors.Is(err, media.ErrUnsupportedType) {
+		respondWithError(w, http.StatusUnsupportedMediaType, "only JPEG, PNG and GIF images are supported", err)
+		return
+	}
+
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "invalid image", err)
+		return
+	}
+
+	mediaID := uuid.New()
+	storageKey := mediaID.String() + extensionFor(img.ContentType)
+	thumbnailKey := mediaID.String() + "_thumb" + extensionFor(img.ThumbnailContentType)
+
+	err = c.storage.Put(r.Context(), storageKey, bytes.NewReader(img.Data))
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "error while storing image", err)
+		return
+	}
+
+	err = c.storage.Put(r.Context(), thumbnailKey, bytes.NewReader(img.Thumbnail))
+	if err != nil {
+		c.storage.Delete(r.Context(), storageKey)
+		respondWithError(w, http.StatusInternalServerError, "error while storing thumbnail", err)
+		return
+	}
+
+	row, err := c.db.CreateMedia(r.Context(), database.CreateMediaParams{
+		ID:           mediaID,
+		UserID:       userID,
+		ContentType:  img.ContentType,
+		StorageKey:   storageKey,
+		ThumbnailKey: thumbnailKey,
+		Width:        int32(img.Width),
+		Height:       int32(img.Height),
+		SizeBytes:    int64(len(img.Data)),
+	})
+	if err != nil {
+		c.storage.Delete(r.Context(), storageKey)
+		c.storage.Delete(r.Context(), thumbnailKey)
+		respondWithError(w, http.StatusInternalServerError, "error while saving media", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusCreated, mediaFromDB(row))
+}
+
+func (c *apiConfig) ServeMedia(w http.ResponseWriter, r *http.Request) {
+	key := r.PathValue("key")
+
+	blob, err := c.storage.Open(r.Context(), key)
+	if errors.Is(err, storage.ErrNotFound) {
+		respondWithError(w, http.StatusNotFound, "media not found", err)
+		return
+	}
+
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "error while reading media", err)
+		return
+	}
+	defer blob.Close()
+
+	w.Header().Set("Content-Type", mime.TypeByExtension(path.Ext(key)))
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+	w.WriteHeader(http.StatusOK)
+	io.Copy(w, blob)
+}
+
+var errInvalidMedia = errors.New("media must be your own uploads and not attached to another chirp")
+
+// uniqueViolation is the Postgres error code for a broken unique constraint.
+const uniqueViolation = "23505"
+
+// attachChirpMedia links uploaded media to a new chirp in the order given by
+// mediaIDs. It must run in the chirp's transaction. Media another chirp
+// claims concurrently passes the check but then breaks chirp_media's unique
+// constraint, which is reported as errInvalidMedia too.
+func attachChirpMedia(ctx context.Context, q *database.Queries, userID, chirpID uuid.UUID, mediaIDs []uuid.UUID) error {
+	if len(mediaIDs) == 0 {
+		return nil
+	}
+
+	rows, err := q.GetUnattachedMedia(ctx, database.GetUnattachedMediaParams{
+		Ids:    mediaIDs,
+		UserID: userID,
+	})
+	if err != nil {
+		return err
+	}
+
+	if len(rows) != len(mediaIDs) {
+		return errInvalidMedia
+	}
+
+	for i, mediaID := range mediaIDs {
+		err = q.AttachMedia(ctx, database.AttachMediaParams{
+			ChirpID:  chirpID,
+			MediaID:  mediaID,
+			Position: int32(i),
+		})
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == uniqueViolation {
+			return errInvalidMedia
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// attachChirpMediaInfo loads the media of every chirp with a single query.
+// Tombstones never show media.
+func (c *apiConfig) attachChirpMediaInfo(ctx context.Context, chirps ...*Chirp) error {
+	ids := make([]uuid.UUID, 0, len(chirps))
+	for _, chirp := range chirps {
+		if !chirp.Deleted {
+			ids = append(ids, chirp.ID)
+		}
+	}
+
+	if len(ids) == 0 {
+		return nil
+	}
+
+	rows, err := c.db.ListMediaForChirps(ctx, ids)
+	if err != nil {
+		return err
+	}
+
+	byChirp := map[uuid.UUID][]Media{}
+	for _, row := range rows {
+		byChirp[row.ChirpID] = append(byChirp[row.ChirpID], mediaFromDB(row.Medium))
+	}
+
+	for _, chirp := range chirps {
+		chirp.Media = byChirp[chirp.ID]
+	}
+
+	return nil
+}
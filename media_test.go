@@ -0,0 +1,32 @@
+package main
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"testing"
+
+	"github.com/adi290491/chirpy/internal/database"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+func TestAttachChirpMediaRace(t *testing.T) {
+	userID := uuid.New()
+	medium := database.Medium{ID: uuid.New(), UserID: userID}
+
+	// the check passes, but another chirp attached the media before the insert
+	c := newTestConfig(t, map[string]fakeQuery{
+		"GetUnattachedMedia": func([]driver.Value) ([][]driver.Value, error) {
+			return modelRows(medium), nil
+		},
+		"AttachMedia": func([]driver.Value) ([][]driver.Value, error) {
+			return nil, &pq.Error{Code: uniqueViolation}
+		},
+	})
+
+	err := attachChirpMedia(context.Background(), c.db, userID, uuid.New(), []uuid.UUID{medium.ID})
+	if !errors.Is(err, errInvalidMedia) {
+		t.Fatalf("expected errInvalidMedia, got %v", err)
+	}
+}
@@ -0,0 +1,319 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/adi290491/chirpy/internal/auth"
+	"github.com/adi290491/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+const (
+	totpIssuer           = "Chirpy"
+	mfaChallengeLifetime = 5 * time.Minute
+)
+
+var (
+	errInvalidSecondFactor = errors.New("invalid or already used code")
+	errNoPendingTOTP       = errors.New("no pending two-factor enrollment")
+)
+
+type MFAChallenge struct {
+	MFARequired bool   `json:"mfa_required"`
+	MFAToken    string `json:"mfa_token"`
+}
+
+func (c *apiConfig) mfaEnabled(ctx context.Context, userID uuid.UUID) (bool, error) {
+	totp, err := c.db.GetTOTPByUser(ctx, userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	return totp.ConfirmedAt.Valid, nil
+}
+
+// verifySecondFactor accepts either a TOTP code or a recovery code and burns
+// it, so neither can be replayed.
+func verifySecondFactor(ctx context.Context, q *database.Queries, userID uuid.UUID, code string) error {
+	if auth.IsRecoveryCode(code) {
+		used, err := q.UseRecoveryCode(ctx, database.UseRecoveryCodeParams{
+			UserID:   userID,
+			CodeHash: auth.HashRecoveryCode(code),
+		})
+		if err != nil {
+			return err
+		}
+		if used == 0 {
+			return errInvalidSecondFactor
+		}
+		return nil
+	}
+
+	totp, err := q.GetTOTPByUser(ctx, userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return errInvalidSecondFactor
+	}
+	if err != nil {
+		return err
+	}
+
+	if !totp.ConfirmedAt.Valid {
+		return errInvalidSecondFactor
+	}
+
+	step, err := auth.ValidateTOTP(totp.Secret, code, time.Now())
+	if err != nil {
+		return errInvalidSecondFactor
+	}
+
+	// only a step newer than the last accepted one moves the marker
+	used, err := q.UseTOTPStep(ctx, database.UseTOTPStepParams{
+		UserID:       userID,
+		LastUsedStep: step,
+	})
+	if err != nil {
+		return err
+	}
+	if used == 0 {
+		return errInvalidSecondFactor
+	}
+
+	return nil
+}
+
+func (c *apiConfig) EnrollTOTP(w http.ResponseWriter, r *http.Request) {
+	userID := currentUserID(r)
+
+	user, err := c.db.GetUserByID(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "error while fetching user", err)
+		return
+	}
+
+	secret, err := auth.GenerateTOTPSecret()
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "error while generating secret", err)
+		return
+	}
+
+	_, err = c.db.UpsertPendingTOTP(r.Context(), database.UpsertPendingTOTPParams{
+		UserID: userID,
+		Secret: secret,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, http.StatusConflict, "two-factor authentication is already enabled", err)
+		return
+	}
+
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "error while starting enrollment", err)
+		return
+	}
+
+	type response struct {
+		Secret     string `json:"secret"`
+		OtpauthURI string `json:"otpauth_uri"`
+	}
+
+	respondWithJSON(w, http.StatusOK, response{
+		Secret:     secret,
+		OtpauthURI: auth.TOTPURI(totpIssuer, user.Email, secret),
+	})
+}
+
+func (c *apiConfig) ConfirmTOTP(w http.ResponseWriter, r *http.Request) {
+	userID := currentUserID(r)
+
+	type requestParams struct {
+		Code string `json:"code"`
+	}
+
+	var req requestParams
+	decoder := json.NewDecoder(r.Body)
+	err := decoder.Decode(&req)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "invalid request body", err)
+		return
+	}
+
+	codes, err := auth.GenerateRecoveryCodes()
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "error while generating recovery codes", err)
+		return
+	}
+
+	err = c.withTx(r.Context(), func(q *database.Queries) error {
+		totp, err := q.GetTOTPByUser(r.Context(), userID)
+		if errors.Is(err, sql.ErrNoRows) {
+			return errNoPendingTOTP
+		}
+		if err != nil {
+			return err
+		}
+
+		if totp.ConfirmedAt.Valid {
+			return errNoPendingTOTP
+		}
+
+		step, err := auth.ValidateTOTP(totp.Secret, req.Code, time.Now())
+		if err != nil {
+			return errInvalidSecondFactor
+		}
+
+		confirmed, err := q.ConfirmTOTP(r.Context(), database.ConfirmTOTPParams{
+			UserID:       userID,
+			LastUsedStep: step,
+		})
+		if err != nil {
+			return err
+		}
+		if confirmed == 0 {
+			return errNoPendingTOTP
+		}
+
+		err = q.DeleteRecoveryCodes(r.Context(), userID)
+		if err != nil {
+			return err
+		}
+
+		for _, code := range codes {
+			err = q.CreateRecoveryCode(r.Context(), database.CreateRecoveryCodeParams{
+				UserID:   userID,
+				CodeHash: auth.HashRecoveryCode(code),
+			})
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+
+	if errors.Is(err, errNoPendingTOTP) {
+		respondWithError(w, http.StatusConflict, "start enrollment first", err)
+		return
+	}
+
+	if errors.Is(err, errInvalidSecondFactor) {
+		respondWithError(w, http.StatusBadRequest, "invalid code", err)
+		return
+	}
+
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "error while confirming enrollment", err)
+		return
+	}
+
+	type response struct {
+		RecoveryCodes []string `json:"recovery_codes"`
+	}
+
+	respondWithJSON(w, http.StatusOK, response{
+		RecoveryCodes: codes,
+	})
+}
+
+func (c *apiConfig) DisableTOTP(w http.ResponseWriter, r *http.Request) {
+	userID := currentUserID(r)
+
+	type requestParams struct {
+		Code string `json:"code"`
+	}
+
+	var req requestParams
+	decoder := json.NewDecoder(r.Body)
+	err := decoder.Decode(&req)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "invalid request body", err)
+		return
+	}
+
+	// a stolen access token alone must not be enough to switch 2FA off
+	err = c.withTx(r.Context(), func(q *database.Queries) error {
+		err := verifySecondFactor(r.Context(), q, userID, req.Code)
+		if err != nil {
+			return err
+		}
+
+		err = q.DeleteTOTP(r.Context(), userID)
+		if err != nil {
+			return err
+		}
+
+		return q.DeleteRecoveryCodes(r.Context(), userID)
+	})
+
+	if errors.Is(err, errInvalidSecondFactor) {
+		respondWithError(w, http.StatusForbidden, "invalid code", err)
+		return
+	}
+
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "error while disabling two-factor authentication", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusNoContent, nil)
+}
+
+func (c *apiConfig) LoginMFA(w http.ResponseWriter, r *http.Request) {
+	type requestParams struct {
+		MFAToken string `json:"mfa_token"`
+		Code     string `json:"code"`
+	}
+
+	var req requestParams
+	decoder := json.NewDecoder(r.Body)
+	err := decoder.Decode(&req)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "invalid request body", err)
+		return
+	}
+
+	userID, err := auth.ParseMFAChallenge(req.MFAToken, c.keyring)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "MFA token is invalid or has expired", err)
+		return
+	}
+
+	user, err := c.db.GetUserByID(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "MFA token is invalid or has expired", err)
+		return
+	}
+
+	// codes count against the same counters as passwords, so a challenge
+	// cannot be used to guess through the code space
+	keys := loginThrottleKeys(user.Email, clientFromRequest(r).IP)
+	attempt, ok := c.checkLoginThrottle(w, r, keys, user.Email)
+	if !ok {
+		return
+	}
+
+	err = verifySecondFactor(r.Context(), c.db, userID, req.Code)
+	if errors.Is(err, errInvalidSecondFactor) {
+		c.failLogin(w, r, attempt, auditMFAFailed, uuid.NullUUID{UUID: user.ID, Valid: true}, user.Email, "invalid code")
+		return
+	}
+
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Something went wrong", err)
+		return
+	}
+
+	err = c.refundLoginAttempt(r.Context(), attempt)
+	if err != nil {
+		log.Printf("could not refund login attempt: %v", err)
+	}
+
+	c.completeLogin(w, r, user)
+}
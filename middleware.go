@@ -0,0 +1,152 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/adi290491/chirpy/internal/auth"
+	"github.com/google/uuid"
+)
+
+const authRealm = "chirpy"
+
+var errAuthRequired = errors.New("authentication required")
+
+// respondUnauthorized sends a 401 with the RFC 6750 challenge. A nil cause
+// means no credentials were sent; otherwise the token itself was rejected.
+func respondUnauthorized(w http.ResponseWriter, cause error) {
+	if cause == nil {
+		w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer realm=%q`, authRealm))
+		respondWithError(w, http.StatusUnauthorized, "unauthorized", errAuthRequired)
+		return
+	}
+
+	w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer realm=%q, error="invalid_token", error_description="the access token is invalid or has expired"`, authRealm))
+	respondWithError(w, http.StatusUnauthorized, "unauthorized", cause)
+}
+
+// authenticate verifies the bearer token, if there is one. It reports
+// whether a token was presented at all.
+func (c *apiConfig) authenticate(r *http.Request) (*auth.Claims, bool, error) {
+	if r.Header.Get("Authorization") == "" {
+		return nil, false, nil
+	}
+
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		return nil, true, err
+	}
+
+	if auth.IsAPIToken(token) {
+		claims, err := c.authenticateAPIToken(r.Context(), token)
+		return claims, true, err
+	}
+
+	claims, err := auth.ParseJWT(token, c.keyring)
+	if err != nil {
+		return nil, true, err
+	}
+
+	return claims, true, nil
+}
+
+// respondInsufficientScope sends the RFC 6750 403 for a valid token that may
+// not be used here.
+func respondInsufficientScope(w http.ResponseWriter, scope auth.Scope) {
+	if scope == "" {
+		w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer realm=%q, error="insufficient_scope", error_description="this endpoint needs a login, not an API token"`, authRealm))
+		respondWithError(w, http.StatusForbidden, "forbidden", errors.New("API tokens cannot be used here"))
+		return
+	}
+
+	w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer realm=%q, error="insufficient_scope", scope=%q`, authRealm, scope))
+	respondWithError(w, http.StatusForbidden, "forbidden", fmt.Errorf("token lacks scope %q", scope))
+}
+
+// RequireAuth rejects requests without a valid access token and hands the
+// caller's claims to next through the request context. Personal access
+// tokens are refused; endpoints open to them use RequireScope.
+func (c *apiConfig) RequireAuth(next http.HandlerFunc) http.HandlerFunc {
+	return c.RequireScope("", next)
+}
+
+// RequireScope is RequireAuth that also accepts personal access tokens
+// granted scope.
+func (c *apiConfig) RequireScope(scope auth.Scope, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		claims, presented, err := c.authenticate(r)
+		if !presented {
+			respondUnauthorized(w, nil)
+			return
+		}
+		if err != nil {
+			respondUnauthorized(w, err)
+			return
+		}
+		if !claims.HasScope(scope) {
+			respondInsufficientScope(w, scope)
+			return
+		}
+
+		next(w, r.WithContext(auth.ContextWithClaims(r.Context(), claims)))
+	}
+}
+
+// OptionalAuth lets anonymous requests through, but a token that is present
+// must be valid, so clients learn to refresh instead of silently losing
+// their personalised view. Personal access tokens need scope.
+func (c *apiConfig) OptionalAuth(scope auth.Scope, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		claims, presented, err := c.authenticate(r)
+		if !presented {
+			next(w, r)
+			return
+		}
+		if err != nil {
+			respondUnauthorized(w, err)
+			return
+		}
+		if !claims.HasScope(scope) {
+			respondInsufficientScope(w, scope)
+			return
+		}
+
+		next(w, r.WithContext(auth.ContextWithClaims(r.Context(), claims)))
+	}
+}
+
+// RequirePermission is RequireAuth plus a check that the caller's role grants
+// perm. The role comes from the access token, so a change of role applies
+// once the user's current token is refreshed. Personal access tokens never
+// reach it.
+func (c *apiConfig) RequirePermission(perm auth.Permission, next http.HandlerFunc) http.HandlerFunc {
+	return c.RequireAuth(func(w http.ResponseWriter, r *http.Request) {
+		claims, _ := auth.ClaimsFromContext(r.Context())
+		if !claims.Role.Can(perm) {
+			respondWithError(w, http.StatusForbidden, "forbidden", fmt.Errorf("role %q lacks permission %q", claims.Role, perm))
+			return
+		}
+
+		next(w, r)
+	})
+}
+
+// currentUserID returns the authenticated caller. Only call it from handlers
+// wrapped in RequireAuth.
+func currentUserID(r *http.Request) uuid.UUID {
+	claims, ok := auth.ClaimsFromContext(r.Context())
+	if !ok {
+		return uuid.Nil
+	}
+	return claims.UserID
+}
+
+// viewerID returns the caller of a handler wrapped in OptionalAuth, if any.
+func viewerID(r *http.Request) uuid.NullUUID {
+	claims, ok := auth.ClaimsFromContext(r.Context())
+	if !ok {
+		return uuid.NullUUID{}
+	}
+	return uuid.NullUUID{UUID: claims.UserID, Valid: true}
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"os"
+	"regexp"
+	"time"
+
+	"github.com/adi290491/chirpy/internal/database"
+	"github.com/adi290491/chirpy/internal/moderation"
+	"github.com/google/uuid"
+)
+
+const (
+	defaultWordListPath = "moderation/words.txt"
+)
+
+// moderationRules run after the word list on every chirp body.
+var moderationRules = moderation.RegexFilter{
+	{
+		Pattern: regexp.MustCompile(`(?i)\b(buy|cheap)\s+(followers|likes)\b`),
+		Action:  moderation.Reject,
+		Reason:  "looks like follower spam",
+	},
+	{
+		Pattern: regexp.MustCompile(`(?i)https?://(bit\.ly|tinyurl\.com|goo\.gl)/\S+`),
+		Action:  moderation.Flag,
+		Reason:  "contains a shortened link",
+	},
+}
+
+type ChirpFlag struct {
+	ID        uuid.UUID `json:"id"`
+	ChirpID   uuid.UUID `json:"chirp_id"`
+	Reasons   []string  `json:"reasons"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func (c *apiConfig) initModeration() {
+	path := os.Getenv("MODERATION_WORDLIST")
+	if path == "" {
+		path = defaultWordListPath
+	}
+
+	wordList, err := moderation.NewWordList(path)
+	if err != nil {
+		log.Fatalf("could not load moderation word list: %v", err)
+	}
+
+	c.wordList = wordList
+	c.moderator = moderation.Chain{wordList, moderationRules}
+}
+
+func (c *apiConfig) ReloadWordList(w http.ResponseWriter, r *http.Request) {
+	err := c.wordList.Reload()
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "error while reloading word list", err)
+		return
+	}
+
+	log.Printf("Moderation word list reloaded: %d words", c.wordList.Len())
+
+	respondWithJSON(w, http.StatusOK, struct {
+		Words int `json:"words"`
+	}{
+		Words: c.wordList.Len(),
+	})
+}
+
+func (c *apiConfig) GetChirpFlags(w http.ResponseWriter, r *http.Request) {
+	limit, err := parseLimit(r.URL.Query().Get("limit"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "invalid limit", err)
+		return
+	}
+
+	res, err := c.db.ListOpenChirpFlags(r.Context(), limit)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "error while fetching flags", err)
+		return
+	}
+
+	flags := []ChirpFlag{}
+	for _, row := range res {
+		flags = append(flags, ChirpFlag{
+			ID:        row.ID,
+			ChirpID:   row.ChirpID,
+			Reasons:   row.Reasons,
+			CreatedAt: row.CreatedAt,
+		})
+	}
+
+	respondWithJSON(w, http.StatusOK, flags)
+}
+
+func (c *apiConfig) ResolveChirpFlag(w http.ResponseWriter, r *http.Request) {
+	flagID, err := uuid.Parse(r.PathValue("flagID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "could not parse flag ID", err)
+		return
+	}
+
+	resolved, err := c.db.ResolveChirpFlag(r.Context(), flagID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "error while resolving flag", err)
+		return
+	}
+
+	if resolved == 0 {
+		respondWithError(w, http.StatusNotFound, "flag not found or already resolved", nil)
+		return
+	}
+
+	respondWithJSON(w, http.StatusNoContent, nil)
+}
+
+// flagChirp queues a chirp for review when moderation flagged it.
+func flagChirp(ctx context.Context, q *database.Queries, chirpID uuid.UUID, res moderation.Result) error {
+	if !res.Flagged {
+		return nil
+	}
+
+	_, err := q.CreateChirpFlag(ctx, database.CreateChirpFlagParams{
+		ChirpID: chirpID,
+		Reasons: res.Reasons,
+	})
+	return err
+}
+
+// respondWithRejection reports why moderation refused a chirp.
+func respondWithRejection(w http.ResponseWriter, res moderation.Result) {
+	respondWithJSON(w, http.StatusUnprocessableEntity, struct {
+		Error   string   `json:"error"`
+		Reasons []string `json:"reasons"`
+	}{
+		Error:   "chirp rejected by moderation",
+		Reasons: res.Reasons,
+	})
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	defaultPageLimit = 20
+	maxPageLimit     = 100
+)
+
+// encodeCursor packs the keyset (created_at, id) of the last row of a page
+// into an opaque string that clients send back as ?cursor=.
+func encodeCursor(createdAt time.Time, id uuid.UUID) string {
+	raw := fmt.Sprintf("%s|%s", createdAt.Format(time.RFC3339Nano), id.String())
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func decodeCursor(cursor string) (time.Time, uuid.UUID, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return time.Time{}, uuid.Nil, errors.New("malformed cursor")
+	}
+
+	createdAt, id, found := strings.Cut(string(raw), "|")
+	if !found {
+		return time.Time{}, uuid.Nil, errors.New("malformed cursor")
+	}
+
+	t, err := time.Parse(time.RFC3339Nano, createdAt)
+	if err != nil {
+		return time.Time{}, uuid.Nil, errors.New("malformed cursor")
+	}
+
+	parsedID, err := uuid.Parse(id)
+	if err != nil {
+		return time.Time{}, uuid.Nil, errors.New("malformed cursor")
+	}
+
+	return t, parsedID, nil
+}
+
+// parseCursor turns an optional ?cursor= value into the nullable keyset
+// arguments taken by the List*/GetTimeline queries.
+func parseCursor(cursor string) (sql.NullTime, uuid.NullUUID, error) {
+	if cursor == "" {
+		return sql.NullTime{}, uuid.NullUUID{}, nil
+	}
+
+	createdAt, id, err := decodeCursor(cursor)
+	if err != nil {
+		return sql.NullTime{}, uuid.NullUUID{}, err
+	}
+
+	return sql.NullTime{Time: createdAt, Valid: true}, uuid.NullUUID{UUID: id, Valid: true}, nil
+}
+
+func parseLimit(raw string) (int32, error) {
+	if raw == "" {
+		return defaultPageLimit, nil
+	}
+
+	limit, err := strconv.Atoi(raw)
+	if err != nil || limit < 1 {
+		return 0, errors.New("limit must be a positive integer")
+	}
+
+	if limit > maxPageLimit {
+		limit = maxPageLimit
+	}
+
+	return int32(limit), nil
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/adi290491/chirpy/internal/auth"
+	"github.com/adi290491/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+var (
+	errLastAdmin   = errors.New("cannot demote the last admin")
+	errAdminExists = errors.New("an admin already exists")
+)
+
+// setUserRole changes a user's role, refusing to leave the site without an
+// admin. q must be in a transaction: the admin rows stay locked until it
+// ends, so two admins cannot demote each other at the same time.
+func setUserRole(ctx context.Context, q *database.Queries, userID uuid.UUID, role auth.Role) (database.User, error) {
+	admins, err := q.LockUsersWithRole(ctx, string(auth.RoleAdmin))
+	if err != nil {
+		return database.User{}, err
+	}
+
+	user, err := q.GetUserByID(ctx, userID)
+	if err != nil {
+		return database.User{}, err
+	}
+
+	if auth.Role(user.Role) == auth.RoleAdmin && role != auth.RoleAdmin && len(admins) <= 1 {
+		return database.User{}, errLastAdmin
+	}
+
+	return q.SetUserRole(ctx, database.SetUserRoleParams{
+		Role: string(role),
+		ID:   userID,
+	})
+}
+
+// bootstrapAdmin promotes the user with the given email, but only while the
+// site has no admin at all. Later promotions go through the API.
+func (c *apiConfig) bootstrapAdmin(ctx context.Context, email string) (database.User, error) {
+	var user database.User
+
+	err := c.withTx(ctx, func(q *database.Queries) error {
+		admins, err := q.CountUsersWithRole(ctx, string(auth.RoleAdmin))
+		if err != nil {
+			return err
+		}
+		if admins > 0 {
+			return errAdminExists
+		}
+
+		existing, err := q.GetUserByEmail(ctx, email)
+		if err != nil {
+			return err
+		}
+
+		user, err = setUserRole(ctx, q, existing.ID, auth.RoleAdmin)
+		return err
+	})
+
+	return user, err
+}
+
+func (c *apiConfig) UpdateUserRole(w http.ResponseWriter, r *http.Request) {
+	userID, err := uuid.Parse(r.PathValue("userID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "invalid user ID", err)
+		return
+	}
+
+	type requestParams struct {
+		Role string `json:"role"`
+	}
+
+	var req requestParams
+	decoder := json.NewDecoder(r.Body)
+	err = decoder.Decode(&req)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "invalid request body", err)
+		return
+	}
+
+	role, err := auth.ParseRole(req.Role)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "invalid role", err)
+		return
+	}
+
+	var user database.User
+	err = c.withTx(r.Context(), func(q *database.Queries) error {
+		user, err = setUserRole(r.Context(), q, userID, role)
+		return err
+	})
+
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, http.StatusNotFound, "user not found", err)
+		return
+	}
+
+	if errors.Is(err, errLastAdmin) {
+		respondWithError(w, http.StatusConflict, "promote another admin first", err)
+		return
+	}
+
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "error while updating role", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, User{
+		ID:            user.ID,
+		CreatedAt:     user.CreatedAt,
+		UpdatedAt:     user.UpdatedAt,
+		Email:         user.Email,
+		Handle:        user.Handle,
+		IsChirpyRed:   user.IsChirpyRed,
+		Role:          user.Role,
+		EmailVerified: user.EmailVerifiedAt.Valid,
+	})
+}
@@ -1,13 +1,37 @@
 package main
 
-import "net/http"
+import (
+	"net/http"
+
+	"github.com/adi290491/chirpy/internal/auth"
+)
 
 func (apiCfg *apiConfig) registerRoutes(mux *http.ServeMux, handler http.Handler) {
 	mux.Handle("/app/", apiCfg.middlewareMetricsInc(handler))
 
-	mux.HandleFunc("GET /admin/metrics", apiCfg.GetFileServerHits)
+	mux.HandleFunc("GET /admin/metrics", apiCfg.RequirePermission(auth.PermViewMetrics, apiCfg.GetFileServerHits))
+
+	mux.HandleFunc("POST /admin/reset", apiCfg.RequirePermission(auth.PermResetData, apiCfg.ResetFileServerHits))
+
+	mux.HandleFunc("POST /admin/moderation/reload", apiCfg.RequirePermission(auth.PermModerate, apiCfg.ReloadWordList))
+
+	mux.HandleFunc("GET /admin/moderation/flags", apiCfg.RequirePermission(auth.PermModerate, apiCfg.GetChirpFlags))
+
+	mux.HandleFunc("POST /admin/moderation/flags/{flagID}/resolve", apiCfg.RequirePermission(auth.PermModerate, apiCfg.ResolveChirpFlag))
+
+	mux.HandleFunc("PUT /admin/users/{userID}/role", apiCfg.RequirePermission(auth.PermManageRoles, apiCfg.UpdateUserRole))
+
+	mux.HandleFunc("POST /admin/webhooks", apiCfg.RequirePermission(auth.PermManageWebhooks, apiCfg.CreateWebhookEndpoint(adminWebhooks)))
 
-	mux.HandleFunc("POST /admin/reset", apiCfg.ResetFileServerHits)
+	mux.HandleFunc("GET /admin/webhooks", apiCfg.RequirePermission(auth.PermManageWebhooks, apiCfg.ListWebhookEndpoints(adminWebhooks)))
+
+	mux.HandleFunc("DELETE /admin/webhooks/{id}", apiCfg.RequirePermission(auth.PermManageWebhooks, apiCfg.DeleteWebhookEndpoint(adminWebhooks)))
+
+	mux.HandleFunc("GET /admin/webhooks/{id}/deliveries", apiCfg.RequirePermission(auth.PermManageWebhooks, apiCfg.ListWebhookDeliveries(adminWebhooks)))
+
+	mux.HandleFunc("POST /admin/webhooks/{id}/deliveries/{deliveryID}/retry", apiCfg.RequirePermission(auth.PermManageWebhooks, apiCfg.RetryWebhookDelivery(adminWebhooks)))
+
+	mux.HandleFunc("GET /.well-known/jwks.json", apiCfg.GetJWKS)
 
 	mux.HandleFunc("GET /api/healthz", func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
@@ -19,19 +43,103 @@ func (apiCfg *apiConfig) registerRoutes(mux *http.ServeMux, handler http.Handler
 
 	mux.HandleFunc("POST /api/login", apiCfg.LoginUser)
 
-	mux.HandleFunc("PUT /api/users", apiCfg.UpdateEmailAndPassword)
+	mux.HandleFunc("POST /api/login/mfa", apiCfg.LoginMFA)
+
+	mux.HandleFunc("POST /api/mfa/totp/enroll", apiCfg.RequireAuth(apiCfg.EnrollTOTP))
+
+	mux.HandleFunc("POST /api/mfa/totp/confirm", apiCfg.RequireAuth(apiCfg.ConfirmTOTP))
+
+	mux.HandleFunc("DELETE /api/mfa/totp", apiCfg.RequireAuth(apiCfg.DisableTOTP))
+
+	mux.HandleFunc("POST /api/password-reset", apiCfg.RequestPasswordReset)
+
+	mux.HandleFunc("POST /api/password-reset/confirm", apiCfg.ConfirmPasswordReset)
+
+	mux.HandleFunc("POST /api/verify-email", apiCfg.VerifyEmail)
+
+	mux.HandleFunc("POST /api/verify-email/send", apiCfg.RequireAuth(apiCfg.ResendVerificationEmail))
+
+	mux.HandleFunc("PUT /api/users", apiCfg.RequireAuth(apiCfg.UpdateEmailAndPassword))
+
+	mux.HandleFunc("DELETE /api/users/me", apiCfg.RequireAuth(apiCfg.DeleteAccount))
+
+	mux.HandleFunc("GET /api/users/me/entitlements", apiCfg.RequireAuth(apiCfg.GetEntitlements))
+
+	mux.HandleFunc("GET /api/users/me/export", apiCfg.RequireAuth(apiCfg.ExportAccount))
+
+	mux.HandleFunc("POST /api/users/{userID}/follow", apiCfg.RequireScope(auth.ScopeFollowsWrite, apiCfg.FollowUser))
+
+	mux.HandleFunc("DELETE /api/users/{userID}/follow", apiCfg.RequireScope(auth.ScopeFollowsWrite, apiCfg.UnfollowUser))
+
+	mux.HandleFunc("GET /api/users/{userID}/followers", apiCfg.GetFollowers)
+
+	mux.HandleFunc("GET /api/users/{userID}/following", apiCfg.GetFollowing)
+
+	mux.HandleFunc("GET /api/timeline", apiCfg.RequireScope(auth.ScopeChirpsRead, apiCfg.GetTimeline))
 
 	mux.HandleFunc("POST /api/refresh", apiCfg.RefreshToken)
 
 	mux.HandleFunc("POST /api/revoke", apiCfg.RevokeToken)
 
-	mux.HandleFunc("POST /api/chirps", apiCfg.CreateChirp)
+	mux.HandleFunc("POST /api/tokens", apiCfg.RequireAuth(apiCfg.CreateAPIToken))
+
+	mux.HandleFunc("GET /api/tokens", apiCfg.RequireAuth(apiCfg.ListAPITokens))
+
+	mux.HandleFunc("DELETE /api/tokens/{id}", apiCfg.RequireAuth(apiCfg.RevokeAPIToken))
+
+	mux.HandleFunc("POST /api/webhooks", apiCfg.RequireAuth(apiCfg.CreateWebhookEndpoint(userWebhooks)))
+
+	mux.HandleFunc("GET /api/webhooks", apiCfg.RequireAuth(apiCfg.ListWebhookEndpoints(userWebhooks)))
+
+	mux.HandleFunc("DELETE /api/webhooks/{id}", apiCfg.RequireAuth(apiCfg.DeleteWebhookEndpoint(userWebhooks)))
+
+	mux.HandleFunc("GET /api/webhooks/{id}/deliveries", apiCfg.RequireAuth(apiCfg.ListWebhookDeliveries(userWebhooks)))
+
+	mux.HandleFunc("POST /api/webhooks/{id}/deliveries/{deliveryID}/retry", apiCfg.RequireAuth(apiCfg.RetryWebhookDelivery(userWebhooks)))
+
+	mux.HandleFunc("GET /api/sessions", apiCfg.RequireAuth(apiCfg.ListSessions))
+
+	mux.HandleFunc("DELETE /api/sessions/{id}", apiCfg.RequireAuth(apiCfg.RevokeSession))
+
+	mux.HandleFunc("POST /api/sessions/revoke-all", apiCfg.RequireAuth(apiCfg.RevokeAllSessions))
+
+	mux.HandleFunc("POST /api/media", apiCfg.RequireScope(auth.ScopeChirpsWrite, apiCfg.UploadMedia))
+
+	mux.HandleFunc("GET /media/{key}", apiCfg.ServeMedia)
+
+	mux.HandleFunc("POST /api/chirps", apiCfg.RequireScope(auth.ScopeChirpsWrite, apiCfg.CreateChirp))
+
+	mux.HandleFunc("GET /api/scheduled-chirps", apiCfg.RequireScope(auth.ScopeChirpsRead, apiCfg.ListScheduledChirps))
+
+	mux.HandleFunc("DELETE /api/scheduled-chirps/{id}", apiCfg.RequireScope(auth.ScopeChirpsWrite, apiCfg.CancelScheduledChirp))
+
+	mux.HandleFunc("GET /api/chirps", apiCfg.OptionalAuth(auth.ScopeChirpsRead, apiCfg.GetAllChirps))
+
+	mux.HandleFunc("GET /api/chirps/search", apiCfg.OptionalAuth(auth.ScopeChirpsRead, apiCfg.SearchChirps))
+
+	mux.HandleFunc("GET /api/chirps/{chirpID}", apiCfg.OptionalAuth(auth.ScopeChirpsRead, apiCfg.GetChirpById))
+
+	mux.HandleFunc("PUT /api/chirps/{chirpID}", apiCfg.RequireScope(auth.ScopeChirpsWrite, apiCfg.UpdateChirp))
+
+	mux.HandleFunc("GET /api/chirps/{chirpID}/thread", apiCfg.OptionalAuth(auth.ScopeChirpsRead, apiCfg.GetChirpThread))
+
+	mux.HandleFunc("GET /api/chirps/{chirpID}/revisions", apiCfg.GetChirpRevisions)
+
+	mux.HandleFunc("POST /api/chirps/{chirpID}/like", apiCfg.RequireScope(auth.ScopeChirpsWrite, apiCfg.LikeChirp))
+
+	mux.HandleFunc("DELETE /api/chirps/{chirpID}/like", apiCfg.RequireScope(auth.ScopeChirpsWrite, apiCfg.UnlikeChirp))
+
+	mux.HandleFunc("POST /api/chirps/{chirpID}/rechirp", apiCfg.RequireScope(auth.ScopeChirpsWrite, apiCfg.RechirpChirp))
+
+	mux.HandleFunc("DELETE /api/chirps/{chirpID}/rechirp", apiCfg.RequireScope(auth.ScopeChirpsWrite, apiCfg.UnrechirpChirp))
+
+	mux.HandleFunc("DELETE /api/chirps/{chirpID}", apiCfg.RequireScope(auth.ScopeChirpsWrite, apiCfg.DeleteChirp))
 
-	mux.HandleFunc("GET /api/chirps", apiCfg.GetAllChirps)
+	mux.HandleFunc("GET /api/hashtags/{tag}/chirps", apiCfg.OptionalAuth(auth.ScopeChirpsRead, apiCfg.GetHashtagChirps))
 
-	mux.HandleFunc("GET /api/chirps/{chirpID}", apiCfg.GetChirpById)
+	mux.HandleFunc("GET /api/users/{userID}/mentions", apiCfg.OptionalAuth(auth.ScopeChirpsRead, apiCfg.GetUserMentions))
 
-	mux.HandleFunc("DELETE /api/chirps/{chirpID}", apiCfg.DeleteChirp)
+	mux.HandleFunc("GET /api/trending", apiCfg.GetTrending)
 
 	mux.HandleFunc("POST /api/polka/webhooks", apiCfg.RunWebhook)
 
@@ -0,0 +1,238 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/adi290491/chirpy/internal/database"
+	"github.com/adi290491/chirpy/internal/entitlements"
+	"github.com/adi290491/chirpy/internal/jobs"
+	"github.com/google/uuid"
+)
+
+const (
+	maxScheduleAhead = 365 * 24 * time.Hour
+	// how long a scheduled chirp waits when its author is at the hourly limit
+	scheduledChirpPostpone = 10 * time.Minute
+	// publishing retries transient errors; after this many attempts the
+	// scheduled chirp is dropped
+	maxPublishAttempts = 5
+)
+
+var errTooManyScheduledChirps = errors.New("too many scheduled chirps")
+
+type ScheduledChirp struct {
+	ID        uuid.UUID  `json:"id"`
+	Body      string     `json:"body"`
+	InReplyTo *uuid.UUID `json:"in_reply_to,omitempty"`
+	PublishAt time.Time  `json:"publish_at"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+func scheduledChirpFromDB(row database.ScheduledChirp) ScheduledChirp {
+	chirp := ScheduledChirp{
+		ID:        row.ID,
+		Body:      row.Body,
+		PublishAt: row.PublishAt,
+		CreatedAt: row.CreatedAt,
+	}
+	if row.InReplyTo.Valid {
+		parentID := row.InReplyTo.UUID
+		chirp.InReplyTo = &parentID
+	}
+	return chirp
+}
+
+// scheduleChirp answers a POST /api/chirps that has a publish_at. The body
+// has already passed validation; moderation runs again when it is published,
+// against the rules in force then.
+func (c *apiConfig) scheduleChirp(w http.ResponseWriter, r *http.Request, limits entitlements.Entitlements, body string, inReplyTo uuid.NullUUID, publishAt time.Time) {
+	userID := currentUserID(r)
+
+	if !limits.CanScheduleChirps() {
+		respondWithError(w, http.StatusForbidden, "scheduling chirps is a Chirpy Red feature", nil)
+		return
+	}
+
+	now := time.Now()
+	if !publishAt.After(now) || publishAt.After(now.Add(maxScheduleAhead)) {
+		respondWithError(w, http.StatusBadRequest, "invalid publish_at", errors.New("publish_at must be in the future and within a year"))
+		return
+	}
+
+	var row database.ScheduledChirp
+	err := c.withTx(r.Context(), func(q *database.Queries) error {
+		// concurrent requests by the user wait here, so each counts the last
+		err := q.LockUser(r.Context(), userID)
+		if err != nil {
+			return err
+		}
+
+		pending, err := q.CountScheduledChirpsByUser(r.Context(), userID)
+		if err != nil {
+			return err
+		}
+
+		if pending >= int64(limits.MaxScheduledChirps) {
+			return errTooManyScheduledChirps
+		}
+
+		row, err = q.CreateScheduledChirp(r.Context(), database.CreateScheduledChirpParams{
+			UserID:    userID,
+			Body:      body,
+			InReplyTo: inReplyTo,
+			PublishAt: publishAt.UTC(),
+		})
+		if err != nil {
+			return err
+		}
+
+		_, err = jobs.Enqueue(r.Context(), q, jobPublishScheduledChirp, publishScheduledChirpJob{ID: row.ID}, jobs.At(row.PublishAt), jobs.MaxAttempts(maxPublishAttempts))
+		return err
+	})
+	if errors.Is(err, errTooManyScheduledChirps) {
+		respondWithError(w, http.StatusConflict, "too many scheduled chirps", fmt.Errorf("at most %d chirps can wait to be published", limits.MaxScheduledChirps))
+		return
+	}
+
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "error while scheduling chirp", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusAccepted, scheduledChirpFromDB(row))
+}
+
+func (c *apiConfig) ListScheduledChirps(w http.ResponseWriter, r *http.Request) {
+	rows, err := c.db.ListScheduledChirpsByUser(r.Context(), currentUserID(r))
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "error while fetching scheduled chirps", err)
+		return
+	}
+
+	chirps := []ScheduledChirp{}
+	for _, row := range rows {
+		chirps = append(chirps, scheduledChirpFromDB(row))
+	}
+
+	respondWithJSON(w, http.StatusOK, chirps)
+}
+
+func (c *apiConfig) CancelScheduledChirp(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "invalid scheduled chirp ID", err)
+		return
+	}
+
+	deleted, err := c.db.DeleteScheduledChirp(r.Context(), database.DeleteScheduledChirpParams{
+		ID:     id,
+		UserID: currentUserID(r),
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "error while cancelling scheduled chirp", err)
+		return
+	}
+
+	// already published looks the same as never scheduled
+	if deleted == 0 {
+		respondWithError(w, http.StatusNotFound, "scheduled chirp not found", nil)
+		return
+	}
+
+	respondWithJSON(w, http.StatusNoContent, nil)
+}
+
+type publishScheduledChirpJob struct {
+	ID uuid.UUID `json:"id"`
+}
+
+// publishScheduledChirp turns a scheduled chirp into a real one once it is
+// due. The body is checked again against the author's limits and the
+// moderation rules in force now; one that fails is dropped. An author at the
+// hourly limit has the chirp postponed instead, and one that still cannot be
+// published on its last attempt is dropped so it does not stay in their list
+// forever.
+func (c *apiConfig) publishScheduledChirp(ctx context.Context, payload json.RawMessage) error {
+	var job publishScheduledChirpJob
+	if err := json.Unmarshal(payload, &job); err != nil {
+		return jobs.Permanent(err)
+	}
+
+	err := c.withTx(ctx, func(q *database.Queries) error {
+		row, err := q.GetScheduledChirpForUpdate(ctx, job.ID)
+		// cancelled by its author
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		_, limits, err := c.entitlementsFor(ctx, row.UserID)
+		if err != nil {
+			return err
+		}
+
+		// counted under the same lock as chirps posted directly
+		err = q.LockUser(ctx, row.UserID)
+		if err != nil {
+			return err
+		}
+
+		recent, err := q.CountChirpsByUserSince(ctx, database.CountChirpsByUserSinceParams{
+			UserID:    row.UserID,
+			CreatedAt: time.Now().UTC().Add(-time.Hour),
+		})
+		if err != nil {
+			return err
+		}
+
+		if recent >= int64(limits.ChirpsPerHour) {
+			publishAt := time.Now().UTC().Add(scheduledChirpPostpone)
+			err = q.PostponeScheduledChirp(ctx, database.PostponeScheduledChirpParams{
+				ID:        row.ID,
+				PublishAt: publishAt,
+			})
+			if err != nil {
+				return err
+			}
+
+			_, err = jobs.Enqueue(ctx, q, jobPublishScheduledChirp, job, jobs.At(publishAt), jobs.MaxAttempts(maxPublishAttempts))
+			return err
+		}
+
+		err = q.DeleteScheduledChirpByID(ctx, row.ID)
+		if err != nil {
+			return err
+		}
+
+		moderated, err := c.validateChirp(row.Body, limits.MaxChirpLength)
+		if err != nil {
+			log.Printf("dropped scheduled chirp %s: %v", row.ID, err)
+			return nil
+		}
+		if moderated.Rejected {
+			log.Printf("dropped scheduled chirp %s: rejected by moderation", row.ID)
+			return nil
+		}
+
+		_, err = createChirp(ctx, q, row.UserID, moderated, row.InReplyTo, nil)
+		return err
+	})
+
+	if err != nil && jobs.LastAttempt(ctx) {
+		log.Printf("dropped scheduled chirp %s: %v", job.ID, err)
+		if err := c.db.DeleteScheduledChirpByID(ctx, job.ID); err != nil {
+			log.Printf("could not drop scheduled chirp %s: %v", job.ID, err)
+		}
+	}
+
+	return err
+}
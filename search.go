@@ -0,0 +1,82 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/adi290491/chirpy/internal/database"
+	"github.com/adi290491/chirpy/internal/search"
+	"github.com/google/uuid"
+)
+
+type SearchResult struct {
+	Chirp
+	Rank      float32 `json:"rank"`
+	Highlight string  `json:"highlight"`
+}
+
+func (c *apiConfig) SearchChirps(w http.ResponseWriter, r *http.Request) {
+	query, err := search.ToTSQuery(r.URL.Query().Get("q"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "invalid search query", err)
+		return
+	}
+
+	limit, err := parseLimit(r.URL.Query().Get("limit"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "invalid limit", err)
+		return
+	}
+
+	var offset int
+	if raw := r.URL.Query().Get("offset"); raw != "" {
+		offset, err = strconv.Atoi(raw)
+		if err != nil || offset < 0 {
+			respondWithError(w, http.StatusBadRequest, "invalid offset", err)
+			return
+		}
+	}
+
+	var authorID uuid.NullUUID
+	if authorId := r.URL.Query().Get("author_id"); authorId != "" {
+		userID, err := uuid.Parse(authorId)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "error while parsing author id", err)
+			return
+		}
+		authorID = uuid.NullUUID{UUID: userID, Valid: true}
+	}
+
+	res, err := c.db.SearchChirps(r.Context(), database.SearchChirpsParams{
+		Query:    query,
+		AuthorID: authorID,
+		Limit:    limit,
+		Offset:   int32(offset),
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "error while searching chirps", err)
+		return
+	}
+
+	results := []SearchResult{}
+	for _, row := range res {
+		results = append(results, SearchResult{
+			Chirp:     chirpFromDB(row.Chirp),
+			Rank:      row.Rank,
+			Highlight: search.HighlightHTML(row.Highlight),
+		})
+	}
+
+	refs := make([]*Chirp, 0, len(results))
+	for i := range results {
+		refs = append(refs, &results[i].Chirp)
+	}
+
+	err = c.hydrateChirps(r.Context(), viewerID(r), refs...)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "error while fetching chirp details", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, results)
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/adi290491/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+const maxUserAgentLength = 512
+
+// sessionClient is what a refresh token remembers about the device that
+// received it.
+type sessionClient struct {
+	UserAgent string
+	IP        string
+}
+
+func clientFromRequest(r *http.Request) sessionClient {
+	userAgent := truncateText(r.UserAgent(), maxUserAgentLength)
+
+	ip, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		ip = r.RemoteAddr
+	}
+
+	return sessionClient{
+		UserAgent: userAgent,
+		IP:        ip,
+	}
+}
+
+type Session struct {
+	ID         uuid.UUID  `json:"id"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+	UserAgent  string     `json:"user_agent"`
+	IP         string     `json:"ip"`
+}
+
+func (c *apiConfig) ListSessions(w http.ResponseWriter, r *http.Request) {
+	userID := currentUserID(r)
+
+	rows, err := c.db.ListSessionsByUser(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "error while fetching sessions", err)
+		return
+	}
+
+	sessions := []Session{}
+	for _, row := range rows {
+		session := Session{
+			ID:        row.FamilyID,
+			CreatedAt: row.StartedAt,
+			ExpiresAt: row.ExpiresAt,
+			UserAgent: row.UserAgent,
+			IP:        row.IpAddress,
+		}
+		if row.LastUsedAt.Valid {
+			session.LastUsedAt = &row.LastUsedAt.Time
+		}
+		sessions = append(sessions, session)
+	}
+
+	respondWithJSON(w, http.StatusOK, sessions)
+}
+
+func (c *apiConfig) RevokeSession(w http.ResponseWriter, r *http.Request) {
+	userID := currentUserID(r)
+
+	sessionID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "invalid session ID", err)
+		return
+	}
+
+	revoked, err := c.db.RevokeSession(r.Context(), database.RevokeSessionParams{
+		FamilyID: sessionID,
+		UserID:   userID,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "error while revoking session", err)
+		return
+	}
+
+	// someone else's session looks the same as a missing one
+	if revoked == 0 {
+		respondWithError(w, http.StatusNotFound, "session not found", nil)
+		return
+	}
+
+	respondWithJSON(w, http.StatusNoContent, nil)
+}
+
+func (c *apiConfig) RevokeAllSessions(w http.ResponseWriter, r *http.Request) {
+	userID := currentUserID(r)
+
+	err := c.db.RevokeAllSessions(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "error while revoking sessions", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusNoContent, nil)
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestClientFromRequest(t *testing.T) {
+	tests := []struct {
+		name      string
+		userAgent string
+		want      string
+	}{
+		{
+			name:      "short",
+			userAgent: "Mozilla/5.0",
+			want:      "Mozilla/5.0",
+		},
+		{
+			name:      "long ASCII",
+			userAgent: strings.Repeat("a", maxUserAgentLength+10),
+			want:      strings.Repeat("a", maxUserAgentLength),
+		},
+		{
+			name:      "character across the limit",
+			userAgent: strings.Repeat("a", maxUserAgentLength-1) + "é",
+			want:      strings.Repeat("a", maxUserAgentLength-1),
+		},
+		{
+			name:      "invalid bytes",
+			userAgent: "Mozilla\xff/5.0",
+			want:      "Mozilla/5.0",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/api/login", nil)
+			req.Header.Set("User-Agent", tt.userAgent)
+			req.RemoteAddr = testIP + ":4000"
+
+			client := clientFromRequest(req)
+			if client.UserAgent != tt.want {
+				t.Errorf("expected user agent %q, got %q", tt.want, client.UserAgent)
+			}
+			if !utf8.ValidString(client.UserAgent) {
+				t.Errorf("expected valid UTF-8, got %q", client.UserAgent)
+			}
+			if client.IP != testIP {
+				t.Errorf("expected IP %s, got %s", testIP, client.IP)
+			}
+		})
+	}
+}
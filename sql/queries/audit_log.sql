@@ -0,0 +1,11 @@
+-- name: CreateAuditEvent :exec
+INSERT INTO audit_log (id, created_at, event, user_id, email, ip_address, user_agent)
+VALUES (
+    gen_random_uuid(),
+    NOW(),
+    $1,
+    $2,
+    $3,
+    $4,
+    $5
+);
@@ -0,0 +1,21 @@
+-- name: CreateChirpFlag :one
+INSERT INTO chirp_flags (id, chirp_id, reasons, created_at)
+VALUES (
+    gen_random_uuid(),
+    $1,
+    $2,
+    NOW()
+)
+RETURNING *;
+
+-- name: ListOpenChirpFlags :many
+SELECT * FROM chirp_flags
+WHERE resolved_at IS NULL
+ORDER BY created_at ASC
+LIMIT $1;
+
+-- name: ResolveChirpFlag :execrows
+UPDATE chirp_flags
+SET resolved_at = NOW()
+WHERE id = $1
+AND resolved_at IS NULL;
@@ -0,0 +1,40 @@
+-- name: LikeChirp :exec
+INSERT INTO chirp_likes (user_id, chirp_id, created_at)
+VALUES (
+    $1,
+    $2,
+    NOW()
+)
+ON CONFLICT DO NOTHING;
+
+-- name: UnlikeChirp :exec
+DELETE FROM chirp_likes
+WHERE user_id = $1
+AND chirp_id = $2;
+
+-- name: RechirpChirp :exec
+INSERT INTO chirp_rechirps (user_id, chirp_id, created_at)
+VALUES (
+    $1,
+    $2,
+    NOW()
+)
+ON CONFLICT DO NOTHING;
+
+-- name: UnrechirpChirp :exec
+DELETE FROM chirp_rechirps
+WHERE user_id = $1
+AND chirp_id = $2;
+
+-- name: GetChirpStats :many
+SELECT
+    c.id AS chirp_id,
+    (SELECT COUNT(*) FROM chirp_likes l WHERE l.chirp_id = c.id) AS like_count,
+    (SELECT COUNT(*) FROM chirp_rechirps rc WHERE rc.chirp_id = c.id) AS rechirp_count,
+    EXISTS (
+        SELECT 1 FROM chirp_likes l
+        WHERE l.chirp_id = c.id
+        AND l.user_id = sqlc.narg('viewer_id')::uuid
+    ) AS liked_by_me
+FROM chirps c
+WHERE c.id = ANY(sqlc.arg('chirp_ids')::uuid[]);
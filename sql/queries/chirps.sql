@@ -1,11 +1,12 @@
 -- name: CreateChirp :one
-INSERT INTO chirps (id, created_at, updated_at, body, user_id)
+INSERT INTO chirps (id, created_at, updated_at, body, user_id, in_reply_to)
 VALUES (
     gen_random_uuid(),
     NOW(),
     NOW(),
     $1,
-    $2
+    $2,
+    $3
 )
 RETURNING *;
 
@@ -14,17 +15,126 @@ DELETE FROM chirps;
 
 -- name: GetAllChirps :many
 SELECT * FROM chirps
+WHERE deleted_at IS NULL
 ORDER BY created_at ASC;
 
 -- name: GetAllChirpsByUserId :many
 SELECT * FROM chirps
 WHERE user_id = $1
+AND deleted_at IS NULL
 ORDER BY created_at ASC;
 
 -- name: GetChirpById :one
 SELECT * FROM chirps
-WHERE id = $1;
+WHERE id = $1
+AND deleted_at IS NULL;
 
 -- name: DeleteChirpByID :exec
 DELETE FROM chirps
-WHERE id = $1;
\ No newline at end of file
+WHERE id = $1;
+
+-- name: ListChirpsAsc :many
+SELECT * FROM chirps
+WHERE deleted_at IS NULL
+AND (sqlc.narg('author_id')::uuid IS NULL OR user_id = sqlc.narg('author_id'))
+AND (
+    sqlc.narg('cursor_created_at')::timestamp IS NULL
+    OR (created_at, id) > (sqlc.narg('cursor_created_at'), sqlc.narg('cursor_id')::uuid)
+)
+ORDER BY created_at ASC, id ASC
+LIMIT sqlc.arg('limit');
+
+-- name: ListChirpsDesc :many
+SELECT * FROM chirps
+WHERE deleted_at IS NULL
+AND (sqlc.narg('author_id')::uuid IS NULL OR user_id = sqlc.narg('author_id'))
+AND (
+    sqlc.narg('cursor_created_at')::timestamp IS NULL
+    OR (created_at, id) < (sqlc.narg('cursor_created_at'), sqlc.narg('cursor_id')::uuid)
+)
+ORDER BY created_at DESC, id DESC
+LIMIT sqlc.arg('limit');
+
+-- name: TombstoneChirp :exec
+UPDATE chirps
+SET body = '',
+deleted_at = NOW(),
+updated_at = NOW()
+WHERE id = $1;
+
+-- name: LockChirpsByUser :exec
+-- Replies' foreign key checks wait on these locks, so none can be added to
+-- the user's chirps until the transaction ends.
+SELECT id FROM chirps
+WHERE user_id = $1
+FOR UPDATE;
+
+-- name: OrphanRepliedChirpsByUser :execrows
+-- Tombstones the user's chirps that have replies and detaches them from the
+-- user, so they outlive the account and keep its threads together.
+UPDATE chirps
+SET body = '',
+deleted_at = COALESCE(deleted_at, NOW()),
+updated_at = NOW(),
+user_id = NULL
+WHERE user_id = $1
+AND EXISTS (SELECT 1 FROM chirps replies WHERE replies.in_reply_to = chirps.id);
+
+-- name: CountReplies :one
+SELECT COUNT(*) FROM chirps
+WHERE in_reply_to = $1;
+
+-- name: CountChirpsByUserSince :one
+SELECT COUNT(*) FROM chirps
+WHERE user_id = $1
+AND created_at > $2;
+
+-- name: GetChirpAncestors :many
+WITH RECURSIVE ancestors AS (
+    SELECT parent.*, 1 AS depth FROM chirps parent
+    WHERE parent.id = (SELECT c.in_reply_to FROM chirps c WHERE c.id = $1)
+    UNION ALL
+    SELECT p.*, a.depth + 1 FROM chirps p
+    JOIN ancestors a ON p.id = a.in_reply_to
+)
+SELECT id, created_at, updated_at, body, user_id, in_reply_to, deleted_at, search_vector FROM ancestors
+ORDER BY depth DESC;
+
+-- name: GetChirpDescendants :many
+WITH RECURSIVE descendants AS (
+    SELECT child.* FROM chirps child
+    WHERE child.in_reply_to = $1
+    UNION ALL
+    SELECT c.* FROM chirps c
+    JOIN descendants d ON c.in_reply_to = d.id
+)
+SELECT id, created_at, updated_at, body, user_id, in_reply_to, deleted_at, search_vector FROM descendants
+ORDER BY created_at ASC, id ASC;
+
+-- name: GetChirpByIdForUpdate :one
+SELECT * FROM chirps
+WHERE id = $1
+AND deleted_at IS NULL
+FOR UPDATE;
+
+-- name: UpdateChirpBody :one
+UPDATE chirps
+SET body = $1,
+updated_at = NOW()
+WHERE id = $2
+RETURNING *;
+
+-- name: SearchChirps :many
+-- Matches are delimited with U+E000 and U+E001 rather than markup, so the
+-- body can be escaped before it is shown; see search.HighlightHTML.
+SELECT
+    sqlc.embed(chirps),
+    ts_rank(chirps.search_vector, query)::real AS rank,
+    ts_headline('english', chirps.body, query, 'StartSel=' || chr(57344) || ', StopSel=' || chr(57345) || ', HighlightAll=true')::text AS highlight
+FROM chirps, to_tsquery('english', sqlc.arg('query')) query
+WHERE chirps.deleted_at IS NULL
+AND chirps.search_vector @@ query
+AND (sqlc.narg('author_id')::uuid IS NULL OR chirps.user_id = sqlc.narg('author_id'))
+ORDER BY rank DESC, chirps.created_at DESC, chirps.id DESC
+LIMIT sqlc.arg('limit')
+OFFSET sqlc.arg('offset');
@@ -0,0 +1,28 @@
+-- name: CreateEmailToken :one
+INSERT INTO email_tokens (id, user_id, purpose, email, created_at, expires_at)
+VALUES (
+    gen_random_uuid(),
+    $1,
+    $2,
+    $3,
+    NOW(),
+    $4
+)
+RETURNING *;
+
+-- name: UseEmailToken :one
+-- Redeems a token once; returns no row if it is unknown, used or expired.
+UPDATE email_tokens
+SET used_at = NOW()
+WHERE id = $1
+AND purpose = $2
+AND used_at IS NULL
+AND expires_at > NOW()
+RETURNING *;
+
+-- name: InvalidateEmailTokens :exec
+UPDATE email_tokens
+SET used_at = NOW()
+WHERE user_id = $1
+AND purpose = $2
+AND used_at IS NULL;
@@ -0,0 +1,49 @@
+-- name: EnqueueJob :one
+INSERT INTO jobs (id, kind, payload, max_attempts, run_at, created_at, updated_at)
+VALUES (
+    gen_random_uuid(),
+    $1,
+    $2,
+    $3,
+    $4,
+    NOW(),
+    NOW()
+)
+RETURNING id;
+
+-- name: ClaimJobs :many
+-- Counts an attempt and moves run_at to lease_until, so a job whose worker
+-- dies is picked up again once the lease runs out.
+WITH due AS (
+    SELECT id FROM jobs
+    WHERE status = 'pending'
+    AND run_at <= NOW()
+    ORDER BY run_at ASC
+    LIMIT sqlc.arg('limit')
+    FOR UPDATE SKIP LOCKED
+)
+UPDATE jobs
+SET attempts = jobs.attempts + 1,
+run_at = sqlc.arg('lease_until')::timestamp,
+updated_at = NOW()
+FROM due
+WHERE jobs.id = due.id
+RETURNING jobs.*;
+
+-- name: CompleteJob :exec
+DELETE FROM jobs
+WHERE id = $1;
+
+-- name: RetryJob :exec
+UPDATE jobs
+SET run_at = $2,
+last_error = $3,
+updated_at = NOW()
+WHERE id = $1;
+
+-- name: FailJob :exec
+UPDATE jobs
+SET status = 'failed',
+last_error = $2,
+updated_at = NOW()
+WHERE id = $1;
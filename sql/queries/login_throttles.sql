@@ -0,0 +1,34 @@
+-- name: LockLoginThrottle :one
+-- Creates the key's row if it is new and locks it until the transaction ends.
+INSERT INTO login_throttles (key, failures, last_failed_at, blocked_until)
+VALUES ($1, 0, NOW(), 'epoch')
+ON CONFLICT (key) DO UPDATE
+SET key = EXCLUDED.key
+RETURNING blocked_until;
+
+-- name: RecordLoginFailure :one
+-- The count starts over once a key has had no failures for a day.
+INSERT INTO login_throttles (key, failures, last_failed_at, blocked_until)
+VALUES ($1, 1, NOW(), 'epoch')
+ON CONFLICT (key) DO UPDATE
+SET failures = CASE
+        WHEN login_throttles.last_failed_at < NOW() - INTERVAL '1 day' THEN 1
+        ELSE login_throttles.failures + 1
+    END,
+    last_failed_at = NOW()
+RETURNING failures;
+
+-- name: RefundLoginAttempt :one
+UPDATE login_throttles
+SET failures = GREATEST(failures - 1, 0)
+WHERE key = $1
+RETURNING failures;
+
+-- name: SetLoginBlockedUntil :exec
+UPDATE login_throttles
+SET blocked_until = $2
+WHERE key = $1;
+
+-- name: ClearLoginThrottle :exec
+DELETE FROM login_throttles
+WHERE key = $1;
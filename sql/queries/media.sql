@@ -0,0 +1,49 @@
+-- name: CreateMedia :one
+INSERT INTO media (id, user_id, content_type, storage_key, thumbnail_key, width, height, size_bytes, created_at)
+VALUES (
+    $1,
+    $2,
+    $3,
+    $4,
+    $5,
+    $6,
+    $7,
+    $8,
+    NOW()
+)
+RETURNING *;
+
+-- name: GetUnattachedMedia :many
+SELECT * FROM media
+WHERE id = ANY(sqlc.arg('ids')::uuid[])
+AND user_id = sqlc.arg('user_id')
+AND NOT EXISTS (
+    SELECT 1 FROM chirp_media
+    WHERE chirp_media.media_id = media.id
+);
+
+-- name: AttachMedia :exec
+INSERT INTO chirp_media (chirp_id, media_id, position)
+VALUES (
+    $1,
+    $2,
+    $3
+);
+
+-- name: DeleteMediaForChirp :many
+DELETE FROM media
+USING chirp_media
+WHERE chirp_media.media_id = media.id
+AND chirp_media.chirp_id = $1
+RETURNING media.*;
+
+-- name: ListMediaForChirps :many
+SELECT chirp_media.chirp_id, sqlc.embed(media) FROM chirp_media
+JOIN media ON media.id = chirp_media.media_id
+WHERE chirp_media.chirp_id = ANY(sqlc.arg('chirp_ids')::uuid[])
+ORDER BY chirp_media.chirp_id, chirp_media.position;
+
+-- name: ListMediaByUser :many
+SELECT * FROM media
+WHERE user_id = $1
+ORDER BY created_at ASC;
@@ -0,0 +1,40 @@
+-- name: CreateScheduledChirp :one
+INSERT INTO scheduled_chirps (id, user_id, body, in_reply_to, publish_at, created_at)
+VALUES (
+    gen_random_uuid(),
+    $1,
+    $2,
+    $3,
+    $4,
+    NOW()
+)
+RETURNING *;
+
+-- name: CountScheduledChirpsByUser :one
+SELECT COUNT(*) FROM scheduled_chirps
+WHERE user_id = $1;
+
+-- name: ListScheduledChirpsByUser :many
+SELECT * FROM scheduled_chirps
+WHERE user_id = $1
+ORDER BY publish_at ASC;
+
+-- name: DeleteScheduledChirp :execrows
+DELETE FROM scheduled_chirps
+WHERE id = $1
+AND user_id = $2;
+
+-- name: GetScheduledChirpForUpdate :one
+-- Locks the row so a cancel cannot race the publish.
+SELECT * FROM scheduled_chirps
+WHERE id = $1
+FOR UPDATE;
+
+-- name: PostponeScheduledChirp :exec
+UPDATE scheduled_chirps
+SET publish_at = $2
+WHERE id = $1;
+
+-- name: DeleteScheduledChirpByID :exec
+DELETE FROM scheduled_chirps
+WHERE id = $1;
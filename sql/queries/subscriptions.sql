@@ -0,0 +1,48 @@
+-- name: GetSubscriptionByUser :one
+SELECT * FROM subscriptions
+WHERE user_id = $1;
+
+-- name: GetSubscriptionByUserForUpdate :one
+SELECT * FROM subscriptions
+WHERE user_id = $1
+FOR UPDATE;
+
+-- name: ActivateSubscription :one
+-- A late or repeated event never moves the period end backwards.
+INSERT INTO subscriptions (user_id, plan, status, current_period_end, created_at, updated_at)
+VALUES ($1, $2, 'active', $3, NOW(), NOW())
+ON CONFLICT (user_id) DO UPDATE
+SET plan = EXCLUDED.plan,
+status = 'active',
+current_period_end = GREATEST(subscriptions.current_period_end, EXCLUDED.current_period_end),
+updated_at = NOW()
+RETURNING *;
+
+-- name: RecordSubscriptionEvent :exec
+UPDATE subscriptions
+SET last_event_at = GREATEST(last_event_at, sqlc.arg('occurred_at')::timestamp)
+WHERE user_id = $1;
+
+-- name: SetSubscriptionStatus :execrows
+UPDATE subscriptions
+SET status = $2,
+updated_at = NOW()
+WHERE user_id = $1;
+
+-- name: ExpireLapsedSubscriptions :many
+-- Ends every subscription whose period ran out before lapsed_before without a
+-- renewal, and takes Chirpy Red away from its user.
+WITH lapsed AS (
+    UPDATE subscriptions
+    SET status = 'expired',
+    updated_at = NOW()
+    WHERE status IN ('active', 'past_due')
+    AND current_period_end < sqlc.arg(lapsed_before)::timestamp
+    RETURNING user_id
+)
+UPDATE users
+SET is_chirpy_red = false,
+updated_at = NOW()
+FROM lapsed
+WHERE users.id = lapsed.user_id
+RETURNING users.id;
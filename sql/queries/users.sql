@@ -1,11 +1,12 @@
 -- name: CreateUser :one
-INSERT INTO users (id, created_at, updated_at, hashed_password, email) 
+INSERT INTO users (id, created_at, updated_at, hashed_password, email, handle) 
 VALUES (
     gen_random_uuid(),
     NOW(),
     NOW(),
     $1,
-    $2
+    $2,
+    $3
 )
 RETURNING *;
 
@@ -21,15 +22,61 @@ SELECT * FROM users
 WHERE id = $1;
 
 -- name: UpdateUser :one
+-- A new address has to be verified again.
 UPDATE users
-SET email = $1,
+SET email_verified_at = CASE WHEN email = $1 THEN email_verified_at END,
+email = $1,
 hashed_password = $2,
+handle = $3,
 updated_at = NOW()
-WHERE id = $3
+WHERE id = $4
 RETURNING *;
 
 -- name: UpdateUserSubscription :exec
 UPDATE users
 SET is_chirpy_red = $1,
 updated_at = NOW()
-WHERE id = $2;
\ No newline at end of file
+WHERE id = $2;
+
+-- name: DeleteUserByID :exec
+DELETE FROM users
+WHERE id = $1;
+
+-- name: SetUserRole :one
+UPDATE users
+SET role = $1,
+updated_at = NOW()
+WHERE id = $2
+RETURNING *;
+
+-- name: CountUsersWithRole :one
+SELECT COUNT(*) FROM users
+WHERE role = $1;
+
+-- name: LockUser :exec
+-- Serialises a user's writes that check a limit against a count, such as
+-- posting within the hourly chirp limit, until the transaction ends. NO KEY
+-- UPDATE leaves foreign key checks on the user free to go ahead.
+SELECT id FROM users
+WHERE id = $1
+FOR NO KEY UPDATE;
+
+-- name: LockUsersWithRole :many
+-- Holds the rows until the transaction ends, so concurrent role changes
+-- count the same users.
+SELECT id FROM users
+WHERE role = $1
+FOR UPDATE;
+
+-- name: UpdateUserPassword :exec
+UPDATE users
+SET hashed_password = $1,
+updated_at = NOW()
+WHERE id = $2;
+
+-- name: MarkEmailVerified :execrows
+UPDATE users
+SET email_verified_at = NOW(),
+updated_at = NOW()
+WHERE id = $1
+AND email = $2;
@@ -0,0 +1,60 @@
+-- name: EnqueueWebhookDeliveries :many
+-- Records a delivery of the event for every endpoint subscribed to it: the
+-- admin endpoints and those of the user the event is about.
+INSERT INTO webhook_deliveries (id, endpoint_id, event, payload, next_attempt_at, created_at, updated_at)
+SELECT gen_random_uuid(), id, sqlc.arg('event')::text, sqlc.arg('payload')::jsonb, NOW(), NOW(), NOW()
+FROM webhook_endpoints
+WHERE sqlc.arg('event')::text = ANY(events)
+AND (user_id IS NULL OR user_id = sqlc.arg('user_id')::uuid)
+RETURNING id;
+
+-- name: StartWebhookDeliveryAttempt :one
+-- Counts an attempt at a pending delivery and returns what to send.
+UPDATE webhook_deliveries
+SET attempts = webhook_deliveries.attempts + 1,
+updated_at = NOW()
+FROM webhook_endpoints
+WHERE webhook_deliveries.id = $1
+AND webhook_deliveries.status = 'pending'
+AND webhook_endpoints.id = webhook_deliveries.endpoint_id
+RETURNING webhook_deliveries.id, webhook_deliveries.event, webhook_deliveries.payload, webhook_deliveries.attempts, webhook_endpoints.url, webhook_endpoints.secret;
+
+-- name: RecordWebhookDeliverySuccess :exec
+UPDATE webhook_deliveries
+SET status = 'succeeded',
+last_status_code = $2,
+last_error = NULL,
+delivered_at = NOW(),
+updated_at = NOW()
+WHERE id = $1;
+
+-- name: RecordWebhookDeliveryFailure :exec
+UPDATE webhook_deliveries
+SET status = $2,
+next_attempt_at = $3,
+last_status_code = $4,
+last_error = $5,
+updated_at = NOW()
+WHERE id = $1;
+
+-- name: ListWebhookDeliveries :many
+SELECT * FROM webhook_deliveries
+WHERE endpoint_id = sqlc.arg('endpoint_id')
+AND (sqlc.narg('status')::text IS NULL OR status = sqlc.narg('status'))
+AND (
+    sqlc.narg('cursor_created_at')::timestamp IS NULL
+    OR (created_at, id) < (sqlc.narg('cursor_created_at'), sqlc.narg('cursor_id')::uuid)
+)
+ORDER BY created_at DESC, id DESC
+LIMIT sqlc.arg('limit');
+
+-- name: RetryWebhookDelivery :execrows
+-- Puts a dead delivery back in the queue with a fresh set of attempts.
+UPDATE webhook_deliveries
+SET status = 'pending',
+attempts = 0,
+next_attempt_at = NOW(),
+updated_at = NOW()
+WHERE id = $1
+AND endpoint_id = $2
+AND status = 'dead';
@@ -0,0 +1,32 @@
+-- name: CreateWebhookEndpoint :one
+INSERT INTO webhook_endpoints (id, user_id, url, secret, events, created_at, updated_at)
+VALUES (
+    gen_random_uuid(),
+    $1,
+    $2,
+    $3,
+    $4,
+    NOW(),
+    NOW()
+)
+RETURNING *;
+
+-- name: CountWebhookEndpointsByOwner :one
+-- A NULL owner counts the admin endpoints.
+SELECT COUNT(*) FROM webhook_endpoints
+WHERE user_id IS NOT DISTINCT FROM $1;
+
+-- name: ListWebhookEndpointsByOwner :many
+SELECT * FROM webhook_endpoints
+WHERE user_id IS NOT DISTINCT FROM $1
+ORDER BY created_at DESC;
+
+-- name: GetWebhookEndpointByOwner :one
+SELECT * FROM webhook_endpoints
+WHERE id = $1
+AND user_id IS NOT DISTINCT FROM $2;
+
+-- name: DeleteWebhookEndpoint :execrows
+DELETE FROM webhook_endpoints
+WHERE id = $1
+AND user_id IS NOT DISTINCT FROM $2;
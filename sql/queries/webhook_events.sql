@@ -0,0 +1,5 @@
+-- name: RecordWebhookEvent :execrows
+-- Affects no rows when the event was already recorded.
+INSERT INTO webhook_events (provider, id, event, received_at)
+VALUES ($1, $2, $3, NOW())
+ON CONFLICT (provider, id) DO NOTHING;
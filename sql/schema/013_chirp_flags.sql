@@ -0,0 +1,13 @@
+-- +goose up
+CREATE TABLE chirp_flags (
+    id UUID PRIMARY KEY,
+    chirp_id UUID NOT NULL REFERENCES chirps(id) ON DELETE CASCADE,
+    reasons TEXT[] NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    resolved_at TIMESTAMP
+);
+
+CREATE INDEX chirp_flags_open_idx ON chirp_flags (created_at) WHERE resolved_at IS NULL;
+
+-- +goose down
+DROP TABLE chirp_flags;
@@ -0,0 +1,12 @@
+-- +goose up
+ALTER TABLE refresh_tokens RENAME COLUMN token TO token_hash;
+
+-- re-key live tokens in place so nobody is logged out; this matches
+-- auth.HashRefreshToken, the SHA-256 of the hex token, hex encoded
+UPDATE refresh_tokens SET token_hash = encode(sha256(convert_to(token_hash, 'UTF8')), 'hex');
+
+-- +goose down
+-- hashes cannot be turned back into tokens, so every session ends
+DELETE FROM refresh_tokens;
+
+ALTER TABLE refresh_tokens RENAME COLUMN token_hash TO token;
@@ -0,0 +1,22 @@
+-- +goose up
+ALTER TABLE users
+ADD COLUMN email_verified_at TIMESTAMP;
+
+-- one row per emailed token; the row makes the signed token single-use
+CREATE TABLE email_tokens (
+    id UUID PRIMARY KEY,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    purpose TEXT NOT NULL,
+    email TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    expires_at TIMESTAMP NOT NULL,
+    used_at TIMESTAMP
+);
+
+CREATE INDEX email_tokens_user_id_idx ON email_tokens (user_id, purpose);
+
+-- +goose down
+DROP TABLE email_tokens;
+
+ALTER TABLE users
+DROP COLUMN email_verified_at;
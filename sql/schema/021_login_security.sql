@@ -0,0 +1,26 @@
+-- +goose up
+-- failed logins per key, where a key is an account ("account:<email>") or a
+-- client address ("ip:<address>")
+CREATE TABLE login_throttles (
+    key TEXT PRIMARY KEY,
+    failures INTEGER NOT NULL,
+    last_failed_at TIMESTAMP NOT NULL,
+    blocked_until TIMESTAMP NOT NULL
+);
+
+CREATE TABLE audit_log (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    event TEXT NOT NULL,
+    user_id UUID REFERENCES users(id) ON DELETE SET NULL,
+    email TEXT NOT NULL DEFAULT '',
+    ip_address TEXT NOT NULL DEFAULT '',
+    user_agent TEXT NOT NULL DEFAULT ''
+);
+
+CREATE INDEX audit_log_user_id_idx ON audit_log (user_id, created_at);
+
+-- +goose down
+DROP TABLE audit_log;
+
+DROP TABLE login_throttles;
@@ -0,0 +1,12 @@
+-- +goose up
+-- every webhook event applied, so redeliveries can be recognised
+CREATE TABLE webhook_events (
+    provider TEXT NOT NULL,
+    id TEXT NOT NULL,
+    event TEXT NOT NULL,
+    received_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (provider, id)
+);
+
+-- +goose down
+DROP TABLE webhook_events;
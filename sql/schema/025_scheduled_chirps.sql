@@ -0,0 +1,21 @@
+-- +goose up
+-- chirps waiting to be published; a row becomes a chirp at publish_at
+CREATE TABLE scheduled_chirps (
+    id UUID PRIMARY KEY,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    body TEXT NOT NULL,
+    in_reply_to UUID REFERENCES chirps(id) ON DELETE CASCADE,
+    publish_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX scheduled_chirps_publish_at_idx ON scheduled_chirps (publish_at);
+CREATE INDEX scheduled_chirps_user_id_idx ON scheduled_chirps (user_id);
+
+-- the hourly chirp limit counts a user's recent chirps
+CREATE INDEX chirps_user_id_created_at_idx ON chirps (user_id, created_at);
+
+-- +goose down
+DROP INDEX chirps_user_id_created_at_idx;
+
+DROP TABLE scheduled_chirps;
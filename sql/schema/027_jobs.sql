@@ -0,0 +1,21 @@
+-- +goose up
+-- background work queued by handlers in the same transaction as their writes;
+-- a job is deleted once it succeeds
+CREATE TABLE jobs (
+    id UUID PRIMARY KEY,
+    kind TEXT NOT NULL,
+    payload JSONB NOT NULL,
+    status TEXT NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'failed')),
+    attempts INTEGER NOT NULL DEFAULT 0,
+    max_attempts INTEGER NOT NULL,
+    run_at TIMESTAMP NOT NULL,
+    last_error TEXT,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX jobs_due_idx ON jobs (run_at)
+WHERE status = 'pending';
+
+-- +goose down
+DROP TABLE jobs;
@@ -0,0 +1,37 @@
+-- +goose up
+-- the length limit depends on the author's tier, so it lives in
+-- internal/entitlements rather than in the column type. search_vector is
+-- generated from body and has to be rebuilt around the type change.
+DROP INDEX chirps_search_vector_idx;
+
+ALTER TABLE chirps
+DROP COLUMN search_vector;
+
+ALTER TABLE chirps
+ALTER COLUMN body TYPE TEXT;
+
+ALTER TABLE chirps
+ADD COLUMN search_vector tsvector GENERATED ALWAYS AS (to_tsvector('english', body)) STORED;
+
+CREATE INDEX chirps_search_vector_idx ON chirps USING GIN (search_vector);
+
+ALTER TABLE chirp_revisions
+ALTER COLUMN body TYPE TEXT;
+
+-- +goose down
+-- longer Chirpy Red bodies are cut to fit
+ALTER TABLE chirp_revisions
+ALTER COLUMN body TYPE VARCHAR(140) USING left(body, 140);
+
+DROP INDEX chirps_search_vector_idx;
+
+ALTER TABLE chirps
+DROP COLUMN search_vector;
+
+ALTER TABLE chirps
+ALTER COLUMN body TYPE VARCHAR(140) USING left(body, 140);
+
+ALTER TABLE chirps
+ADD COLUMN search_vector tsvector GENERATED ALWAYS AS (to_tsvector('english', body)) STORED;
+
+CREATE INDEX chirps_search_vector_idx ON chirps USING GIN (search_vector);
@@ -0,0 +1,10 @@
+-- +goose up
+-- scheduled chirps are published by the job queue; queue a job for each one
+-- already waiting
+INSERT INTO jobs (id, kind, payload, max_attempts, run_at, created_at, updated_at)
+SELECT gen_random_uuid(), 'chirp.publish_scheduled', jsonb_build_object('id', id), 5, publish_at, NOW(), NOW()
+FROM scheduled_chirps;
+
+-- +goose down
+DELETE FROM jobs
+WHERE kind = 'chirp.publish_scheduled';
@@ -0,0 +1,22 @@
+-- +goose up
+-- tombstones used to keep their media; delete it along with the files, as
+-- deleting a chirp now does
+INSERT INTO jobs (id, kind, payload, max_attempts, run_at, created_at, updated_at)
+SELECT gen_random_uuid(), 'storage.delete_blobs', jsonb_build_object('keys', jsonb_agg(k.key)), 10, NOW(), NOW(), NOW()
+FROM (
+    SELECT unnest(ARRAY[media.storage_key, media.thumbnail_key]) AS key
+    FROM media
+    JOIN chirp_media ON chirp_media.media_id = media.id
+    JOIN chirps ON chirps.id = chirp_media.chirp_id
+    WHERE chirps.deleted_at IS NOT NULL
+) k
+HAVING COUNT(*) > 0;
+
+DELETE FROM media
+USING chirp_media, chirps
+WHERE chirp_media.media_id = media.id
+AND chirps.id = chirp_media.chirp_id
+AND chirps.deleted_at IS NOT NULL;
+
+-- +goose down
+-- the files are gone, so there is nothing to restore
@@ -0,0 +1,16 @@
+-- +goose up
+-- webhook deliveries are sent by the job queue; queue a job for each one still
+-- pending, carrying over the attempts it already made
+INSERT INTO jobs (id, kind, payload, attempts, max_attempts, run_at, created_at, updated_at)
+SELECT gen_random_uuid(), 'webhook.deliver', jsonb_build_object('delivery_id', id), attempts, GREATEST(attempts + 1, 20), next_attempt_at, NOW(), NOW()
+FROM webhook_deliveries
+WHERE status = 'pending';
+
+DROP INDEX webhook_deliveries_due_idx;
+
+-- +goose down
+CREATE INDEX webhook_deliveries_due_idx ON webhook_deliveries (next_attempt_at)
+WHERE status = 'pending';
+
+DELETE FROM jobs
+WHERE kind = 'webhook.deliver';
@@ -0,0 +1,7 @@
+-- +goose up
+-- when the newest Polka event applied to the subscription happened, so one
+-- that arrives out of order can be ignored
+ALTER TABLE subscriptions ADD COLUMN last_event_at TIMESTAMP;
+
+-- +goose down
+ALTER TABLE subscriptions DROP COLUMN last_event_at;
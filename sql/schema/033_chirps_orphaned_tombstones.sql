@@ -0,0 +1,8 @@
+-- +goose up
+-- a deleted account leaves tombstones behind for chirps that have replies;
+-- they no longer belong to anyone
+ALTER TABLE chirps ALTER COLUMN user_id DROP NOT NULL;
+
+-- +goose down
+DELETE FROM chirps WHERE user_id IS NULL;
+ALTER TABLE chirps ALTER COLUMN user_id SET NOT NULL;
@@ -0,0 +1,15 @@
+-- +goose up
+-- mentions name a user by handle, so they no longer publish an email
+-- address; existing accounts get a placeholder they can change
+ALTER TABLE users ADD COLUMN handle TEXT;
+
+UPDATE users SET handle = 'user_' || left(replace(id::text, '-', ''), 12);
+
+ALTER TABLE users ALTER COLUMN handle SET NOT NULL;
+
+CREATE UNIQUE INDEX users_handle_idx ON users (lower(handle));
+
+-- +goose down
+DROP INDEX users_handle_idx;
+
+ALTER TABLE users DROP COLUMN handle;
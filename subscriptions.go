@@ -0,0 +1,166 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/adi290491/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+const (
+	defaultPlan = "chirpy_red"
+
+	// subscriptionPeriod is assumed when an event does not say when the
+	// period ends.
+	subscriptionPeriod = 30 * 24 * time.Hour
+	// subscriptionGrace is how long past the period end Chirpy Red is kept
+	// while waiting for a renewal that may be late.
+	subscriptionGrace         = 3 * 24 * time.Hour
+	subscriptionSweepInterval = 10 * time.Minute
+)
+
+// Subscription statuses.
+const (
+	subscriptionActive   = "active"
+	subscriptionPastDue  = "past_due"
+	subscriptionCanceled = "canceled"
+	subscriptionExpired  = "expired"
+)
+
+// polkaEventHandler applies one kind of Polka event inside the transaction
+// that records it.
+type polkaEventHandler func(ctx context.Context, q *database.Queries, data Data) error
+
+type userUpgradedEvent struct {
+	UserID           uuid.UUID `json:"user_id"`
+	Plan             string    `json:"plan"`
+	CurrentPeriodEnd time.Time `json:"current_period_end"`
+}
+
+// activateSubscription handles both the first upgrade and each renewal.
+func activateSubscription(ctx context.Context, q *database.Queries, data Data) error {
+	plan := data.Plan
+	if plan == "" {
+		plan = defaultPlan
+	}
+
+	periodEnd := time.Now().UTC().Add(subscriptionPeriod)
+	if data.CurrentPeriodEnd != nil {
+		periodEnd = data.CurrentPeriodEnd.UTC()
+	}
+
+	user, err := q.GetUserByID(ctx, data.UserID)
+	if err != nil {
+		return err
+	}
+
+	sub, err := q.ActivateSubscription(ctx, database.ActivateSubscriptionParams{
+		UserID:           data.UserID,
+		Plan:             plan,
+		CurrentPeriodEnd: periodEnd,
+	})
+	if err != nil {
+		return err
+	}
+
+	err = q.UpdateUserSubscription(ctx, database.UpdateUserSubscriptionParams{
+		IsChirpyRed: true,
+		ID:          data.UserID,
+	})
+	if err != nil {
+		return err
+	}
+
+	// renewals keep a member on Red; only the move onto it is announced
+	if user.IsChirpyRed {
+		return nil
+	}
+
+	return emitEvent(ctx, q, eventUserUpgraded, data.UserID, userUpgradedEvent{
+		UserID:           data.UserID,
+		Plan:             sub.Plan,
+		CurrentPeriodEnd: sub.CurrentPeriodEnd,
+	})
+}
+
+// markSubscriptionPastDue keeps Chirpy Red for now; Polka retries the
+// payment, and if it never succeeds the period runs out and the sweep ends
+// the subscription.
+func markSubscriptionPastDue(ctx context.Context, q *database.Queries, data Data) error {
+	_, err := q.SetSubscriptionStatus(ctx, database.SetSubscriptionStatusParams{
+		UserID: data.UserID,
+		Status: subscriptionPastDue,
+	})
+	return err
+}
+
+// subscriptionEventIsStale locks the user's subscription and reports whether
+// a newer event than one that happened at occurredAt was already applied to it.
+func subscriptionEventIsStale(ctx context.Context, q *database.Queries, userID uuid.UUID, occurredAt time.Time) (bool, error) {
+	sub, err := q.GetSubscriptionByUserForUpdate(ctx, userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	return sub.LastEventAt.Valid && occurredAt.Before(sub.LastEventAt.Time), nil
+}
+
+// endSubscription returns a handler that takes Chirpy Red away at once,
+// unless the event names a period that a renewal has since extended.
+func endSubscription(status string) polkaEventHandler {
+	return func(ctx context.Context, q *database.Queries, data Data) error {
+		if data.CurrentPeriodEnd != nil {
+			sub, err := q.GetSubscriptionByUserForUpdate(ctx, data.UserID)
+			if err != nil && !errors.Is(err, sql.ErrNoRows) {
+				return err
+			}
+			if err == nil && sub.CurrentPeriodEnd.After(*data.CurrentPeriodEnd) {
+				log.Printf("not ending subscription of user %s: renewed past %s", data.UserID, data.CurrentPeriodEnd.UTC().Format(time.RFC3339))
+				return nil
+			}
+		}
+
+		_, err := q.SetSubscriptionStatus(ctx, database.SetSubscriptionStatusParams{
+			UserID: data.UserID,
+			Status: status,
+		})
+		if err != nil {
+			return err
+		}
+
+		return q.UpdateUserSubscription(ctx, database.UpdateUserSubscriptionParams{
+			IsChirpyRed: false,
+			ID:          data.UserID,
+		})
+	}
+}
+
+// runSubscriptionExpiry ends subscriptions that Polka stopped renewing
+// without telling us, checking every subscriptionSweepInterval.
+func (c *apiConfig) runSubscriptionExpiry(ctx context.Context) {
+	ticker := time.NewTicker(subscriptionSweepInterval)
+	defer ticker.Stop()
+
+	for {
+		expired, err := c.db.ExpireLapsedSubscriptions(ctx, time.Now().UTC().Add(-subscriptionGrace))
+		if err != nil && ctx.Err() == nil {
+			log.Printf("could not expire subscriptions: %v", err)
+		}
+		if len(expired) > 0 {
+			log.Printf("expired %d lapsed Chirpy Red subscriptions", len(expired))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,173 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/adi290491/chirpy/internal/database"
+	"github.com/adi290491/chirpy/internal/tags"
+	"github.com/google/uuid"
+)
+
+const (
+	defaultTrendingWindow = 24 * time.Hour
+	maxTrendingWindow     = 7 * 24 * time.Hour
+	defaultTrendingLimit  = 10
+)
+
+type TrendingHashtag struct {
+	Tag  string `json:"tag"`
+	Uses int64  `json:"uses"`
+}
+
+// indexChirpTags replaces the stored hashtags and mentions of a chirp with
+// the ones found in its current body. Mentions of unknown handles are dropped.
+func indexChirpTags(ctx context.Context, q *database.Queries, chirp database.Chirp) error {
+	err := q.DeleteChirpHashtags(ctx, chirp.ID)
+	if err != nil {
+		return err
+	}
+
+	err = q.DeleteChirpMentions(ctx, chirp.ID)
+	if err != nil {
+		return err
+	}
+
+	for _, tag := range tags.Hashtags(chirp.Body) {
+		err = q.CreateChirpHashtag(ctx, database.CreateChirpHashtagParams{
+			ChirpID: chirp.ID,
+			Tag:     tag,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	handles := tags.Mentions(chirp.Body)
+	if len(handles) == 0 {
+		return nil
+	}
+
+	userIDs, err := q.GetUserIDsByHandles(ctx, handles)
+	if err != nil {
+		return err
+	}
+
+	for _, userID := range userIDs {
+		err = q.CreateChirpMention(ctx, database.CreateChirpMentionParams{
+			ChirpID: chirp.ID,
+			UserID:  userID,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (c *apiConfig) GetHashtagChirps(w http.ResponseWriter, r *http.Request) {
+	tag := strings.ToLower(strings.TrimPrefix(r.PathValue("tag"), "#"))
+
+	limit, err := parseLimit(r.URL.Query().Get("limit"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "invalid limit", err)
+		return
+	}
+
+	cursorCreatedAt, cursorID, err := parseCursor(r.URL.Query().Get("cursor"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "invalid cursor", err)
+		return
+	}
+
+	res, err := c.db.ListChirpsByHashtag(r.Context(), database.ListChirpsByHashtagParams{
+		Tag:             tag,
+		CursorCreatedAt: cursorCreatedAt,
+		CursorID:        cursorID,
+		Limit:           limit + 1,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "error while fetching chirps", err)
+		return
+	}
+
+	c.respondWithChirpPage(w, r, res, limit)
+}
+
+func (c *apiConfig) GetUserMentions(w http.ResponseWriter, r *http.Request) {
+	userID, err := uuid.Parse(r.PathValue("userID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "could not parse user ID", err)
+		return
+	}
+
+	limit, err := parseLimit(r.URL.Query().Get("limit"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "invalid limit", err)
+		return
+	}
+
+	cursorCreatedAt, cursorID, err := parseCursor(r.URL.Query().Get("cursor"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "invalid cursor", err)
+		return
+	}
+
+	res, err := c.db.ListChirpsMentioningUser(r.Context(), database.ListChirpsMentioningUserParams{
+		UserID:          userID,
+		CursorCreatedAt: cursorCreatedAt,
+		CursorID:        cursorID,
+		Limit:           limit + 1,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "error while fetching chirps", err)
+		return
+	}
+
+	c.respondWithChirpPage(w, r, res, limit)
+}
+
+func (c *apiConfig) GetTrending(w http.ResponseWriter, r *http.Request) {
+	window := defaultTrendingWindow
+	if raw := r.URL.Query().Get("window"); raw != "" {
+		parsed, err := time.ParseDuration(raw)
+		if err != nil || parsed <= 0 || parsed > maxTrendingWindow {
+			respondWithError(w, http.StatusBadRequest, "window must be a duration between 0 and 168h", err)
+			return
+		}
+		window = parsed
+	}
+
+	limit := defaultTrendingLimit
+	if raw := r.URL.Query().Get("limit"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed < 1 || parsed > maxPageLimit {
+			respondWithError(w, http.StatusBadRequest, "invalid limit", err)
+			return
+		}
+		limit = parsed
+	}
+
+	res, err := c.db.GetTrendingHashtags(r.Context(), database.GetTrendingHashtagsParams{
+		WindowSeconds: window.Seconds(),
+		Limit:         int32(limit),
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "error while fetching trending hashtags", err)
+		return
+	}
+
+	trending := []TrendingHashtag{}
+	for _, row := range res {
+		trending = append(trending, TrendingHashtag{
+			Tag:  row.Tag,
+			Uses: row.Uses,
+		})
+	}
+
+	respondWithJSON(w, http.StatusOK, trending)
+}
@@ -0,0 +1,22 @@
+package main
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// truncateText makes s safe to store in a TEXT column of at most max bytes.
+// Postgres rejects text that is not valid UTF-8, so invalid bytes are dropped
+// and the cut is made on a character boundary.
+func truncateText(s string, max int) string {
+	s = strings.ToValidUTF8(s, "")
+	if len(s) <= max {
+		return s
+	}
+
+	end := max
+	for end > 0 && !utf8.RuneStart(s[end]) {
+		end--
+	}
+	return s[:end]
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/google/uuid"
+)
+
+type ThreadNode struct {
+	Chirp
+	Replies []*ThreadNode `json:"replies"`
+}
+
+type ChirpThread struct {
+	Ancestors []Chirp     `json:"ancestors"`
+	Chirp     *ThreadNode `json:"chirp"`
+}
+
+func (c *apiConfig) GetChirpThread(w http.ResponseWriter, r *http.Request) {
+	chirpID, err := uuid.Parse(r.PathValue("chirpID"))
+
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "could not parse chirp ID", err)
+		return
+	}
+
+	chirp, err := c.db.GetChirpById(r.Context(), chirpID)
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "error while fetching chirp", err)
+		return
+	}
+
+	ancestors, err := c.db.GetChirpAncestors(r.Context(), chirpID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "error while fetching thread", err)
+		return
+	}
+
+	descendants, err := c.db.GetChirpDescendants(r.Context(), uuid.NullUUID{UUID: chirpID, Valid: true})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "error while fetching thread", err)
+		return
+	}
+
+	thread := ChirpThread{
+		Ancestors: []Chirp{},
+		Chirp:     &ThreadNode{Chirp: chirpFromDB(chirp), Replies: []*ThreadNode{}},
+	}
+
+	for _, row := range ancestors {
+		thread.Ancestors = append(thread.Ancestors, chirpFromDB(row))
+	}
+
+	refs := chirpRefs(thread.Ancestors)
+	refs = append(refs, &thread.Chirp.Chirp)
+
+	// descendants arrive oldest first, so every parent is indexed before its replies
+	nodes := map[uuid.UUID]*ThreadNode{chirpID: thread.Chirp}
+	for _, row := range descendants {
+		parent, ok := nodes[row.InReplyTo.UUID]
+		if !ok {
+			continue
+		}
+		node := &ThreadNode{Chirp: chirpFromDB(row), Replies: []*ThreadNode{}}
+		parent.Replies = append(parent.Replies, node)
+		nodes[row.ID] = node
+		refs = append(refs, &node.Chirp)
+	}
+
+	err = c.hydrateChirps(r.Context(), viewerID(r), refs...)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "error while fetching chirp details", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, thread)
+}
@@ -0,0 +1,197 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/adi290491/chirpy/internal/auth"
+	"github.com/adi290491/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+// An account gets a few tries before each failure doubles the wait. A single
+// address may be guessing at many accounts, so it gets more room but a
+// longer lockout.
+var (
+	accountThrottle = auth.Throttle{
+		Free:    3,
+		Base:    time.Second,
+		LockAt:  10,
+		Lockout: 15 * time.Minute,
+	}
+	ipThrottle = auth.Throttle{
+		Free:    20,
+		Base:    time.Second,
+		LockAt:  100,
+		Lockout: time.Hour,
+	}
+)
+
+// Password reset requests and verification emails each send an email, so
+// their counters count requests rather than failures. An inbox gets one
+// email, then has to wait a minute, doubling each time; an address can ask
+// for a few inboxes.
+var (
+	emailSendThrottle = auth.Throttle{
+		Free:    0,
+		Base:    time.Minute,
+		LockAt:  6,
+		Lockout: 24 * time.Hour,
+	}
+	emailSendIPThrottle = auth.Throttle{
+		Free:    5,
+		Base:    time.Minute,
+		LockAt:  30,
+		Lockout: 24 * time.Hour,
+	}
+)
+
+type throttleKey struct {
+	key    string
+	policy auth.Throttle
+}
+
+// loginThrottleKeys returns the counters a login for email from ip touches.
+// The account counter is keyed by the email as typed, so unknown addresses
+// are throttled exactly like real ones.
+func loginThrottleKeys(email, ip string) []throttleKey {
+	return []throttleKey{
+		{key: accountThrottleKey(email), policy: accountThrottle},
+		{key: "ip:" + ip, policy: ipThrottle},
+	}
+}
+
+func accountThrottleKey(email string) string {
+	return "account:" + strings.ToLower(strings.TrimSpace(email))
+}
+
+// passwordResetThrottleKeys returns the counters a password reset request
+// for email from ip touches. They are separate from the login counters, so
+// asking for resets cannot lock anyone out of logging in.
+func passwordResetThrottleKeys(email, ip string) []throttleKey {
+	return []throttleKey{
+		{key: passwordResetThrottleKey(email), policy: emailSendThrottle},
+		{key: "reset-ip:" + ip, policy: emailSendIPThrottle},
+	}
+}
+
+func passwordResetThrottleKey(email string) string {
+	return "reset:" + strings.ToLower(strings.TrimSpace(email))
+}
+
+// verificationThrottleKeys returns the counters sending a verification
+// email to email for userID from ip touches. Anyone can sign up or change
+// their address to someone else's inbox, so the inbox is counted as well as
+// the account and the address asking.
+func verificationThrottleKeys(userID uuid.UUID, email, ip string) []throttleKey {
+	return []throttleKey{
+		{key: "verify-user:" + userID.String(), policy: emailSendThrottle},
+		{key: "verify:" + strings.ToLower(strings.TrimSpace(email)), policy: emailSendThrottle},
+		{key: "verify-ip:" + ip, policy: emailSendIPThrottle},
+	}
+}
+
+// respondThrottled answers 429 with a Retry-After of wait, rounded up to a
+// whole second.
+func respondThrottled(w http.ResponseWriter, wait time.Duration, msg string) {
+	w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+	respondWithError(w, http.StatusTooManyRequests, msg, nil)
+}
+
+// loginAttempt is a login attempt already counted against its keys.
+type loginAttempt struct {
+	keys []throttleKey
+	// locked is set when counting the attempt started a lockout
+	locked bool
+}
+
+// claimLoginAttempt counts an attempt against every key before any
+// credential is checked, so concurrent guesses cannot all get in under the
+// same count. If a key is still backing off or locked, nothing is counted
+// and the remaining wait is returned instead. An attempt that turns out to be
+// right is taken back with refundLoginAttempt. Requests that send an email,
+// such as password resets, are counted the same way and never refunded.
+func (c *apiConfig) claimLoginAttempt(ctx context.Context, keys []throttleKey) (loginAttempt, time.Duration, error) {
+	attempt := loginAttempt{keys: keys}
+	var wait time.Duration
+
+	err := c.withTx(ctx, func(q *database.Queries) error {
+		// the rows stay locked until commit, so concurrent attempts on a key
+		// queue up and each sees the count the one before it left. Keys are
+		// always locked in the same order, so two logins cannot deadlock.
+		for _, k := range keys {
+			blockedUntil, err := q.LockLoginThrottle(ctx, k.key)
+			if err != nil {
+				return err
+			}
+			wait = max(wait, time.Until(blockedUntil))
+		}
+
+		if wait > 0 {
+			return nil
+		}
+
+		for _, k := range keys {
+			failures, err := q.RecordLoginFailure(ctx, k.key)
+			if err != nil {
+				return err
+			}
+
+			delay := k.policy.Delay(failures)
+			if delay == 0 {
+				continue
+			}
+
+			err = q.SetLoginBlockedUntil(ctx, database.SetLoginBlockedUntilParams{
+				Key:          k.key,
+				BlockedUntil: time.Now().UTC().Add(delay),
+			})
+			if err != nil {
+				return err
+			}
+
+			attempt.locked = attempt.locked || k.policy.Locks(failures)
+		}
+		return nil
+	})
+	if err != nil {
+		return loginAttempt{}, 0, err
+	}
+
+	return attempt, wait, nil
+}
+
+// refundLoginAttempt takes back a counted attempt whose credentials were
+// right, lifting the backoff it set off if the count no longer calls for one.
+func (c *apiConfig) refundLoginAttempt(ctx context.Context, attempt loginAttempt) error {
+	return c.withTx(ctx, func(q *database.Queries) error {
+		for _, k := range attempt.keys {
+			failures, err := q.RefundLoginAttempt(ctx, k.key)
+			if errors.Is(err, sql.ErrNoRows) {
+				continue
+			}
+			if err != nil {
+				return err
+			}
+
+			if k.policy.Delay(failures) > 0 {
+				continue
+			}
+
+			err = q.SetLoginBlockedUntil(ctx, database.SetLoginBlockedUntilParams{
+				Key:          k.key,
+				BlockedUntil: time.Unix(0, 0).UTC(),
+			})
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
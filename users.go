@@ -1,14 +1,19 @@
 package main
 
 import (
+	"database/sql"
 	"encoding/json"
+	"errors"
 	"log"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/adi290491/chirpy/internal/auth"
 	"github.com/adi290491/chirpy/internal/database"
+	"github.com/adi290491/chirpy/internal/tags"
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
 var (
@@ -20,19 +25,43 @@ type LoginRequest struct {
 	Password string `json:"password"`
 }
 
+type UserRequest struct {
+	Email    string `json:"email"`
+	Password string `json:"password"`
+	Handle   string `json:"handle"`
+}
+
 type User struct {
-	ID           uuid.UUID `json:"id"`
-	CreatedAt    time.Time `json:"created_at"`
-	UpdatedAt    time.Time `json:"updated_at"`
-	Email        string    `json:"email"`
-	Token        string    `json:"token"`
-	RefreshToken string    `json:"refresh_token"`
-	IsChirpyRed  bool      `json:"is_chirpy_red"`
+	ID            uuid.UUID `json:"id"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+	Email         string    `json:"email"`
+	Handle        string    `json:"handle"`
+	Token         string    `json:"token"`
+	RefreshToken  string    `json:"refresh_token"`
+	IsChirpyRed   bool      `json:"is_chirpy_red"`
+	Role          string    `json:"role"`
+	EmailVerified bool      `json:"email_verified"`
+}
+
+// usersHandleIndex is the unique index on lower(handle).
+const usersHandleIndex = "users_handle_idx"
+
+// isHandleTaken reports whether err is a write that broke usersHandleIndex.
+func isHandleTaken(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == uniqueViolation && pqErr.Constraint == usersHandleIndex
+}
+
+// defaultHandle names a user who signed up without choosing a handle, in the
+// form migration 034 gave existing accounts.
+func defaultHandle() string {
+	return "user_" + strings.ReplaceAll(uuid.NewString(), "-", "")[:12]
 }
 
 func (c *apiConfig) CreateUser(w http.ResponseWriter, r *http.Request) {
 
-	var params LoginRequest
+	var params UserRequest
 	decoder := json.NewDecoder(r.Body)
 	err := decoder.Decode(&params)
 	if err != nil {
@@ -40,6 +69,14 @@ func (c *apiConfig) CreateUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	handle := params.Handle
+	if handle == "" {
+		handle = defaultHandle()
+	} else if !tags.IsHandle(handle) {
+		respondWithError(w, http.StatusBadRequest, "handle must be 3 to 30 letters, digits or underscores", nil)
+		return
+	}
+
 	hash, err := auth.HashPassword(params.Password)
 
 	if err != nil {
@@ -47,22 +84,39 @@ func (c *apiConfig) CreateUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	user, err := c.db.CreateUser(r.Context(), database.CreateUserParams{
-		HashedPassword: hash,
-		Email:          params.Email,
+	var user database.User
+	err = c.withTx(r.Context(), func(q *database.Queries) error {
+		user, err = q.CreateUser(r.Context(), database.CreateUserParams{
+			HashedPassword: hash,
+			Email:          params.Email,
+			Handle:         handle,
+		})
+		if err != nil {
+			return err
+		}
+
+		return queueVerificationEmail(r.Context(), q, user)
 	})
 
+	if isHandleTaken(err) {
+		respondWithError(w, http.StatusConflict, "handle is already taken", err)
+		return
+	}
+
 	if err != nil {
 		respondWithError(w, http.StatusInternalServerError, "User creation error", err)
 		return
 	}
 
 	resp := User{
-		ID:          user.ID,
-		CreatedAt:   user.CreatedAt,
-		UpdatedAt:   user.UpdatedAt,
-		Email:       user.Email,
-		IsChirpyRed: user.IsChirpyRed,
+		ID:            user.ID,
+		CreatedAt:     user.CreatedAt,
+		UpdatedAt:     user.UpdatedAt,
+		Email:         user.Email,
+		Handle:        user.Handle,
+		IsChirpyRed:   user.IsChirpyRed,
+		Role:          user.Role,
+		EmailVerified: user.EmailVerifiedAt.Valid,
 	}
 	respondWithJSON(w, http.StatusCreated, resp)
 }
@@ -78,14 +132,25 @@ func (c *apiConfig) LoginUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	keys := loginThrottleKeys(userRequest.Email, clientFromRequest(r).IP)
+	attempt, ok := c.checkLoginThrottle(w, r, keys, userRequest.Email)
+	if !ok {
+		return
+	}
+
 	user, err := c.db.GetUserByEmail(r.Context(), userRequest.Email)
+	if errors.Is(err, sql.ErrNoRows) {
+		// spend the same time as a wrong password would
+		auth.CheckDummyPasswordHash(userRequest.Password)
+		c.failLogin(w, r, attempt, auditLoginFailed, uuid.NullUUID{}, userRequest.Email, "incorrect email or password")
+		return
+	}
 
 	if err != nil {
-		respondWithError(w, http.StatusUnauthorized, "incorrect email or password", err)
+		respondWithError(w, http.StatusInternalServerError, "Something went wrong", err)
 		return
 	}
 
-	log.Printf("USER: %+v", user)
 	match, err := auth.CheckPasswordHash(userRequest.Password, user.HashedPassword)
 	if err != nil {
 		respondWithError(w, http.StatusInternalServerError, "Something went wrong", err)
@@ -93,64 +158,128 @@ func (c *apiConfig) LoginUser(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if !match {
-		respondWithError(w, http.StatusUnauthorized, "incorrect email or password", err)
+		c.failLogin(w, r, attempt, auditLoginFailed, uuid.NullUUID{UUID: user.ID, Valid: true}, user.Email, "incorrect email or password")
 		return
 	}
 
-	jwt, err := auth.MakeJWT(user.ID, c.JWT_SECRET, time.Hour*expirationTime)
+	// a second factor, if any, is counted as an attempt of its own
+	err = c.refundLoginAttempt(r.Context(), attempt)
+	if err != nil {
+		log.Printf("could not refund login attempt: %v", err)
+	}
 
+	mfaEnabled, err := c.mfaEnabled(r.Context(), user.ID)
 	if err != nil {
-		respondWithError(w, http.StatusInternalServerError, "JWT token creation failed", err)
+		respondWithError(w, http.StatusInternalServerError, "Something went wrong", err)
 		return
 	}
 
-	refreshToken, err := auth.MakeRefreshToken()
-	if err != nil {
-		respondWithError(w, http.StatusInternalServerError, "Refresh token creation failed", err)
+	if mfaEnabled {
+		challenge, err := auth.MakeMFAChallenge(user.ID, c.keyring, mfaChallengeLifetime)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "MFA challenge creation failed", err)
+			return
+		}
+
+		respondWithJSON(w, http.StatusOK, MFAChallenge{
+			MFARequired: true,
+			MFAToken:    challenge,
+		})
 		return
 	}
 
-	c.db.CreateRefreshToken(r.Context(), database.CreateRefreshTokenParams{
-		Token:     refreshToken,
-		UserID:    user.ID,
-		ExpiresAt: time.Now().Add(60 * 24 * time.Hour),
-	})
+	c.completeLogin(w, r, user)
+}
 
-	respondWithJSON(w, http.StatusOK, User{
-		ID:           user.ID,
-		CreatedAt:    user.CreatedAt,
-		UpdatedAt:    user.UpdatedAt,
-		Email:        user.Email,
-		Token:        jwt,
-		RefreshToken: refreshToken,
-		IsChirpyRed:  user.IsChirpyRed,
-	})
+// checkLoginThrottle counts an attempt against keys before any credential
+// is checked. While any of them is backing off or locked it answers 429 and
+// returns false instead.
+func (c *apiConfig) checkLoginThrottle(w http.ResponseWriter, r *http.Request, keys []throttleKey, email string) (loginAttempt, bool) {
+	attempt, wait, err := c.claimLoginAttempt(r.Context(), keys)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Something went wrong", err)
+		return loginAttempt{}, false
+	}
+
+	if wait == 0 {
+		return attempt, true
+	}
+
+	c.audit(r.Context(), r, auditLoginBlocked, uuid.NullUUID{}, email)
+
+	respondThrottled(w, wait, "too many failed login attempts, try again later")
+	return loginAttempt{}, false
 }
 
-func (c *apiConfig) UpdateEmailAndPassword(w http.ResponseWriter, r *http.Request) {
-	authToken, err := auth.GetBearerToken(r.Header)
+// failLogin audits a failed attempt, which checkLoginThrottle already
+// counted, then answers 401 with msg. Callers pass the same message whether
+// or not the account exists.
+func (c *apiConfig) failLogin(w http.ResponseWriter, r *http.Request, attempt loginAttempt, event string, userID uuid.NullUUID, email, msg string) {
+	c.audit(r.Context(), r, event, userID, email)
+
+	if attempt.locked {
+		c.audit(r.Context(), r, auditAccountLocked, userID, email)
+	}
+
+	respondWithError(w, http.StatusUnauthorized, msg, nil)
+}
+
+// completeLogin issues the access and refresh tokens once every factor has
+// been checked.
+func (c *apiConfig) completeLogin(w http.ResponseWriter, r *http.Request, user database.User) {
+	jwt, err := auth.MakeJWT(user.ID, auth.Role(user.Role), c.keyring, time.Hour*expirationTime)
 
 	if err != nil {
-		respondWithError(w, http.StatusUnauthorized, "Access Token is missing or malformed", err)
+		respondWithError(w, http.StatusInternalServerError, "JWT token creation failed", err)
 		return
 	}
 
-	userID, err := auth.ValidateJWT(authToken, c.JWT_SECRET)
-
+	refreshToken, err := issueRefreshToken(r.Context(), c.db, user.ID, uuid.New(), clientFromRequest(r))
 	if err != nil {
-		respondWithError(w, http.StatusUnauthorized, "invalid token", err)
+		respondWithError(w, http.StatusInternalServerError, "Refresh token creation failed", err)
 		return
 	}
 
-	var userRequest LoginRequest
+	// only a full login resets the account's failures; the address keeps its
+	// count, or one valid account could launder guesses at others
+	err = c.db.ClearLoginThrottle(r.Context(), accountThrottleKey(user.Email))
+	if err != nil {
+		log.Printf("could not clear login throttle: %v", err)
+	}
+
+	c.audit(r.Context(), r, auditLoginSucceeded, uuid.NullUUID{UUID: user.ID, Valid: true}, user.Email)
+
+	respondWithJSON(w, http.StatusOK, User{
+		ID:            user.ID,
+		CreatedAt:     user.CreatedAt,
+		UpdatedAt:     user.UpdatedAt,
+		Email:         user.Email,
+		Handle:        user.Handle,
+		Token:         jwt,
+		RefreshToken:  refreshToken,
+		IsChirpyRed:   user.IsChirpyRed,
+		Role:          user.Role,
+		EmailVerified: user.EmailVerifiedAt.Valid,
+	})
+}
+
+func (c *apiConfig) UpdateEmailAndPassword(w http.ResponseWriter, r *http.Request) {
+	userID := currentUserID(r)
+
+	var userRequest UserRequest
 	decoder := json.NewDecoder(r.Body)
 
-	err = decoder.Decode(&userRequest)
+	err := decoder.Decode(&userRequest)
 	if err != nil {
 		respondWithError(w, http.StatusInternalServerError, "Something went wrong", err)
 		return
 	}
 
+	if userRequest.Handle != "" && !tags.IsHandle(userRequest.Handle) {
+		respondWithError(w, http.StatusBadRequest, "handle must be 3 to 30 letters, digits or underscores", nil)
+		return
+	}
+
 	hashPassword, err := auth.HashPassword(userRequest.Password)
 
 	if err != nil {
@@ -158,22 +287,71 @@ func (c *apiConfig) UpdateEmailAndPassword(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
-	user, err := c.db.UpdateUser(r.Context(), database.UpdateUserParams{
-		Email:          userRequest.Email,
-		HashedPassword: hashPassword,
-		ID:             userID,
+	current, err := c.db.GetUserByID(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "error while fetching user", err)
+		return
+	}
+
+	// an update that leaves the address unverified sends a verification
+	// email, which is throttled like asking for one
+	if current.Email != userRequest.Email || !current.EmailVerifiedAt.Valid {
+		keys := verificationThrottleKeys(userID, userRequest.Email, clientFromRequest(r).IP)
+		_, wait, err := c.claimLoginAttempt(r.Context(), keys)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "User update error", err)
+			return
+		}
+
+		if wait > 0 {
+			respondThrottled(w, wait, "too many verification emails, try again later")
+			return
+		}
+	}
+
+	// the handle is optional; leaving it out keeps the current one
+	handle := userRequest.Handle
+	if handle == "" {
+		handle = current.Handle
+	}
+
+	var user database.User
+	err = c.withTx(r.Context(), func(q *database.Queries) error {
+		user, err = q.UpdateUser(r.Context(), database.UpdateUserParams{
+			Email:          userRequest.Email,
+			HashedPassword: hashPassword,
+			Handle:         handle,
+			ID:             userID,
+		})
+		if err != nil {
+			return err
+		}
+
+		// UpdateUser clears the verification when the address changes
+		if user.EmailVerifiedAt.Valid {
+			return nil
+		}
+		return queueVerificationEmail(r.Context(), q, user)
 	})
 
+	if isHandleTaken(err) {
+		respondWithError(w, http.StatusConflict, "handle is already taken", err)
+		return
+	}
+
 	if err != nil {
 		respondWithError(w, http.StatusInternalServerError, "User update error", err)
 		return
 	}
 
 	respondWithJSON(w, http.StatusOK, User{
-		ID:          user.ID,
-		Email:       user.Email,
-		CreatedAt:   user.CreatedAt,
-		UpdatedAt:   user.UpdatedAt,
-		IsChirpyRed: user.IsChirpyRed,
+		ID:            user.ID,
+		Email:         user.Email,
+		Handle:        user.Handle,
+		CreatedAt:     user.CreatedAt,
+		UpdatedAt:     user.UpdatedAt,
+		IsChirpyRed:   user.IsChirpyRed,
+		Role:          user.Role,
+		EmailVerified: user.EmailVerifiedAt.Valid,
 	})
 }
@@ -0,0 +1,229 @@
+package main
+
+import (
+	"database/sql/driver"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/adi290491/chirpy/internal/auth"
+	"github.com/adi290491/chirpy/internal/database"
+	"github.com/adi290491/chirpy/internal/tags"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+const testPassword = "correct horse"
+
+type throttleRow struct {
+	failures     int32
+	blockedUntil time.Time
+}
+
+// loginStore is the part of the database a login touches.
+type loginStore struct {
+	user        database.User
+	throttles   map[string]*throttleRow
+	audits      []string
+	userLookups int
+}
+
+func newLoginStore(t *testing.T) *loginStore {
+	t.Helper()
+
+	hash, err := auth.HashPassword(testPassword)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	user := testUser()
+	user.HashedPassword = hash
+
+	return &loginStore{
+		user:      user,
+		throttles: map[string]*throttleRow{},
+	}
+}
+
+func (s *loginStore) queries() map[string]fakeQuery {
+	one := func(v driver.Value) [][]driver.Value { return [][]driver.Value{{v}} }
+
+	return map[string]fakeQuery{
+		"LockLoginThrottle": func(args []driver.Value) ([][]driver.Value, error) {
+			key := args[0].(string)
+			if s.throttles[key] == nil {
+				s.throttles[key] = &throttleRow{blockedUntil: time.Unix(0, 0).UTC()}
+			}
+			return one(s.throttles[key].blockedUntil), nil
+		},
+		"RecordLoginFailure": func(args []driver.Value) ([][]driver.Value, error) {
+			row := s.throttles[args[0].(string)]
+			row.failures++
+			return one(int64(row.failures)), nil
+		},
+		"SetLoginBlockedUntil": func(args []driver.Value) ([][]driver.Value, error) {
+			s.throttles[args[0].(string)].blockedUntil = args[1].(time.Time)
+			return nil, nil
+		},
+		"RefundLoginAttempt": func(args []driver.Value) ([][]driver.Value, error) {
+			row := s.throttles[args[0].(string)]
+			if row == nil {
+				return nil, nil
+			}
+			row.failures = max(row.failures-1, 0)
+			return one(int64(row.failures)), nil
+		},
+		"ClearLoginThrottle": func(args []driver.Value) ([][]driver.Value, error) {
+			delete(s.throttles, args[0].(string))
+			return nil, nil
+		},
+		"CreateAuditEvent": func(args []driver.Value) ([][]driver.Value, error) {
+			s.audits = append(s.audits, args[0].(string))
+			return nil, nil
+		},
+		"GetUserByEmail": func(args []driver.Value) ([][]driver.Value, error) {
+			s.userLookups++
+			if args[0] != testEmail {
+				return nil, nil
+			}
+			return modelRows(s.user), nil
+		},
+		"GetTOTPByUser": func([]driver.Value) ([][]driver.Value, error) {
+			return nil, nil
+		},
+		"CreateRefreshToken": func(args []driver.Value) ([][]driver.Value, error) {
+			return modelRows(database.RefreshToken{
+				TokenHash: args[0].(string),
+				UserID:    s.user.ID,
+				ExpiresAt: args[2].(time.Time),
+				FamilyID:  uuid.MustParse(args[3].(string)),
+				UserAgent: args[4].(string),
+				IpAddress: args[5].(string),
+			}), nil
+		},
+	}
+}
+
+func login(c *apiConfig, password string) *httptest.ResponseRecorder {
+	body := `{"email":"` + testEmail + `","password":"` + password + `"}`
+	return serve(c.LoginUser, http.MethodPost, "/api/login", body, nil)
+}
+
+func TestLoginBacksOff(t *testing.T) {
+	store := newLoginStore(t)
+	c := newTestConfig(t, store.queries())
+
+	for i := range accountThrottle.Free + 1 {
+		if w := login(c, "wrong"); w.Code != http.StatusUnauthorized {
+			t.Fatalf("attempt %d: expected 401, got %d", i+1, w.Code)
+		}
+	}
+
+	hits := store.userLookups
+	w := login(c, testPassword)
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected 429 once the free attempts are used up, got %d", w.Code)
+	}
+	if got := w.Header().Get("Retry-After"); got != "1" {
+		t.Errorf("expected Retry-After 1, got %q", got)
+	}
+	if store.userLookups != hits {
+		t.Error("expected a refused login not to look up the account")
+	}
+	if got := store.throttles[accountThrottleKey(testEmail)].failures; got != accountThrottle.Free+1 {
+		t.Errorf("expected a refused login not to be counted, got %d failures", got)
+	}
+	if !slices.Contains(store.audits, auditLoginBlocked) {
+		t.Errorf("expected a %s audit event, got %v", auditLoginBlocked, store.audits)
+	}
+}
+
+func TestLoginLocksOut(t *testing.T) {
+	store := newLoginStore(t)
+	c := newTestConfig(t, store.queries())
+
+	store.throttles[accountThrottleKey(testEmail)] = &throttleRow{
+		failures:     accountThrottle.LockAt - 1,
+		blockedUntil: time.Now().UTC().Add(-time.Second),
+	}
+
+	if w := login(c, "wrong"); w.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401, got %d", w.Code)
+	}
+	if !slices.Contains(store.audits, auditAccountLocked) {
+		t.Errorf("expected a %s audit event, got %v", auditAccountLocked, store.audits)
+	}
+
+	w := login(c, testPassword)
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected a locked account to get 429 even with the right password, got %d", w.Code)
+	}
+	if got, want := w.Header().Get("Retry-After"), "900"; got != want {
+		t.Errorf("expected Retry-After %s, got %q", want, got)
+	}
+}
+
+func TestLoginResetsThrottle(t *testing.T) {
+	store := newLoginStore(t)
+	c := newTestConfig(t, store.queries())
+
+	keyring, err := auth.GenerateKeyring()
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.keyring = keyring
+
+	ipKey := "ip:" + testIP
+	for range 2 {
+		if w := login(c, "wrong"); w.Code != http.StatusUnauthorized {
+			t.Fatalf("expected 401, got %d", w.Code)
+		}
+	}
+
+	if w := login(c, testPassword); w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body)
+	}
+
+	if _, ok := store.throttles[accountThrottleKey(testEmail)]; ok {
+		t.Error("expected a successful login to clear the account's failures")
+	}
+	if got := store.throttles[ipKey].failures; got != 2 {
+		t.Errorf("expected the address to keep its 2 failures, got %d", got)
+	}
+	if !slices.Contains(store.audits, auditLoginSucceeded) {
+		t.Errorf("expected a %s audit event, got %v", auditLoginSucceeded, store.audits)
+	}
+}
+
+func TestCreateUserHandle(t *testing.T) {
+	var handles []string
+	c := newTestConfig(t, map[string]fakeQuery{
+		"CreateUser": func(args []driver.Value) ([][]driver.Value, error) {
+			handles = append(handles, args[2].(string))
+			return nil, &pq.Error{Code: uniqueViolation, Constraint: usersHandleIndex}
+		},
+	})
+
+	createUser := func(body string) *httptest.ResponseRecorder {
+		return serve(c.CreateUser, http.MethodPost, "/api/users", body, nil)
+	}
+
+	if w := createUser(`{"email":"` + testEmail + `","password":"x","handle":"walt.white"}`); w.Code != http.StatusBadRequest {
+		t.Fatalf("expected an invalid handle to get 400, got %d", w.Code)
+	}
+	if len(handles) != 0 {
+		t.Fatalf("expected an invalid handle not to reach the database, got %v", handles)
+	}
+
+	if w := createUser(`{"email":"` + testEmail + `","password":"x","handle":"walt"}`); w.Code != http.StatusConflict {
+		t.Fatalf("expected a taken handle to get 409, got %d: %s", w.Code, w.Body)
+	}
+
+	createUser(`{"email":"` + testEmail + `","password":"x"}`)
+	if len(handles) != 2 || !strings.HasPrefix(handles[1], "user_") || !tags.IsHandle(handles[1]) {
+		t.Fatalf("expected a placeholder handle, got %v", handles)
+	}
+}
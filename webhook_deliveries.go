@@ -0,0 +1,269 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/adi290491/chirpy/internal/database"
+	"github.com/adi290491/chirpy/internal/jobs"
+	"github.com/adi290491/chirpy/internal/webhook"
+	"github.com/google/uuid"
+)
+
+const (
+	webhookSignatureHeader = "Chirpy-Signature"
+	webhookEventHeader     = "Chirpy-Event"
+	webhookDeliveryHeader  = "Chirpy-Delivery"
+
+	webhookSendTimeout = 10 * time.Second
+	// with the job queue's backoff, maxWebhookAttempts spreads retries over
+	// about 12 hours before a delivery is dead-lettered
+	maxWebhookAttempts    = 20
+	maxWebhookErrorLength = 500
+)
+
+// Delivery statuses.
+const (
+	deliveryPending   = "pending"
+	deliverySucceeded = "succeeded"
+	deliveryDead      = "dead"
+)
+
+var deliveryStatuses = []string{deliveryPending, deliverySucceeded, deliveryDead}
+
+type WebhookDelivery struct {
+	ID             uuid.UUID       `json:"id"`
+	Event          string          `json:"event"`
+	Payload        json.RawMessage `json:"payload"`
+	Status         string          `json:"status"`
+	Attempts       int32           `json:"attempts"`
+	NextAttemptAt  *time.Time      `json:"next_attempt_at,omitempty"`
+	LastStatusCode *int32          `json:"last_status_code"`
+	LastError      *string         `json:"last_error"`
+	CreatedAt      time.Time       `json:"created_at"`
+	DeliveredAt    *time.Time      `json:"delivered_at"`
+}
+
+func webhookDeliveryFromDB(row database.WebhookDelivery) WebhookDelivery {
+	delivery := WebhookDelivery{
+		ID:        row.ID,
+		Event:     row.Event,
+		Payload:   row.Payload,
+		Status:    row.Status,
+		Attempts:  row.Attempts,
+		CreatedAt: row.CreatedAt,
+	}
+	if row.Status == deliveryPending {
+		delivery.NextAttemptAt = &row.NextAttemptAt
+	}
+	if row.LastStatusCode.Valid {
+		delivery.LastStatusCode = &row.LastStatusCode.Int32
+	}
+	if row.LastError.Valid {
+		delivery.LastError = &row.LastError.String
+	}
+	if row.DeliveredAt.Valid {
+		delivery.DeliveredAt = &row.DeliveredAt.Time
+	}
+	return delivery
+}
+
+type WebhookDeliveryPage struct {
+	Deliveries []WebhookDelivery `json:"deliveries"`
+	NextCursor string            `json:"next_cursor,omitempty"`
+}
+
+// sendWebhook makes one attempt at a delivery. Anything but a 2xx answer,
+// including a redirect, is a failure; status is 0 when none came back.
+func (c *apiConfig) sendWebhook(ctx context.Context, d database.StartWebhookDeliveryAttemptRow) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, d.Url, bytes.NewReader(d.Payload))
+	if err != nil {
+		return 0, err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "Chirpy-Webhooks/1.0")
+	req.Header.Set(webhookEventHeader, d.Event)
+	req.Header.Set(webhookDeliveryHeader, d.ID.String())
+	req.Header.Set(webhookSignatureHeader, webhook.Sign([]byte(d.Secret), time.Now(), d.Payload))
+
+	resp, err := c.webhookClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 4<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("endpoint answered %s", resp.Status)
+	}
+
+	return resp.StatusCode, nil
+}
+
+// enqueueWebhookDelivery queues a job that sends the delivery with the given
+// ID, in the caller's transaction.
+func enqueueWebhookDelivery(ctx context.Context, q *database.Queries, id uuid.UUID) error {
+	_, err := jobs.Enqueue(ctx, q, jobDeliverWebhook, deliverWebhookJob{DeliveryID: id}, jobs.MaxAttempts(maxWebhookAttempts))
+	return err
+}
+
+type deliverWebhookJob struct {
+	DeliveryID uuid.UUID `json:"delivery_id"`
+}
+
+// deliverWebhook makes one attempt at a delivery and records how it went.
+// The job queue retries failures with its backoff; on the last attempt the
+// delivery is dead-lettered instead, where the delivery log shows it and it
+// can be retried by hand.
+func (c *apiConfig) deliverWebhook(ctx context.Context, payload json.RawMessage) error {
+	var job deliverWebhookJob
+	if err := json.Unmarshal(payload, &job); err != nil {
+		return jobs.Permanent(err)
+	}
+
+	d, err := c.db.StartWebhookDeliveryAttempt(ctx, job.DeliveryID)
+	// the endpoint was deleted, taking its deliveries with it
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	status, sendErr := c.sendWebhook(ctx, d)
+
+	code := sql.NullInt32{Int32: int32(status), Valid: status != 0}
+	if sendErr == nil {
+		return c.db.RecordWebhookDeliverySuccess(ctx, database.RecordWebhookDeliverySuccessParams{
+			ID:             d.ID,
+			LastStatusCode: code,
+		})
+	}
+
+	// the error can carry the endpoint's reason phrase, which may be anything
+	msg := truncateText(sendErr.Error(), maxWebhookErrorLength)
+
+	next := deliveryPending
+	if jobs.LastAttempt(ctx) {
+		next = deliveryDead
+		log.Printf("webhook delivery %s dead after %d attempts: %s", d.ID, d.Attempts, msg)
+	}
+
+	err = c.db.RecordWebhookDeliveryFailure(ctx, database.RecordWebhookDeliveryFailureParams{
+		ID:             d.ID,
+		Status:         next,
+		NextAttemptAt:  time.Now().UTC().Add(jobs.Backoff(d.Attempts)),
+		LastStatusCode: code,
+		LastError:      sql.NullString{String: msg, Valid: true},
+	})
+	if err != nil || next == deliveryDead {
+		return err
+	}
+
+	return sendErr
+}
+
+func (c *apiConfig) ListWebhookDeliveries(owner webhookOwner) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		endpoint, ok := c.ownedWebhookEndpoint(w, r, owner)
+		if !ok {
+			return
+		}
+
+		var status sql.NullString
+		if s := r.URL.Query().Get("status"); s != "" {
+			if !slices.Contains(deliveryStatuses, s) {
+				respondWithError(w, http.StatusBadRequest, "invalid status", fmt.Errorf("status must be one of %v", deliveryStatuses))
+				return
+			}
+			status = sql.NullString{String: s, Valid: true}
+		}
+
+		limit, err := parseLimit(r.URL.Query().Get("limit"))
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "invalid limit", err)
+			return
+		}
+
+		cursorCreatedAt, cursorID, err := parseCursor(r.URL.Query().Get("cursor"))
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "invalid cursor", err)
+			return
+		}
+
+		rows, err := c.db.ListWebhookDeliveries(r.Context(), database.ListWebhookDeliveriesParams{
+			EndpointID:      endpoint.ID,
+			Status:          status,
+			CursorCreatedAt: cursorCreatedAt,
+			CursorID:        cursorID,
+			Limit:           limit + 1,
+		})
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "error while fetching deliveries", err)
+			return
+		}
+
+		page := WebhookDeliveryPage{Deliveries: []WebhookDelivery{}}
+		if len(rows) > int(limit) {
+			rows = rows[:limit]
+			last := rows[len(rows)-1]
+			page.NextCursor = encodeCursor(last.CreatedAt, last.ID)
+		}
+
+		for _, row := range rows {
+			page.Deliveries = append(page.Deliveries, webhookDeliveryFromDB(row))
+		}
+
+		respondWithJSON(w, http.StatusOK, page)
+	}
+}
+
+func (c *apiConfig) RetryWebhookDelivery(owner webhookOwner) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		endpoint, ok := c.ownedWebhookEndpoint(w, r, owner)
+		if !ok {
+			return
+		}
+
+		deliveryID, err := uuid.Parse(r.PathValue("deliveryID"))
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "invalid delivery ID", err)
+			return
+		}
+
+		var retried int64
+		err = c.withTx(r.Context(), func(q *database.Queries) error {
+			retried, err = q.RetryWebhookDelivery(r.Context(), database.RetryWebhookDeliveryParams{
+				ID:         deliveryID,
+				EndpointID: endpoint.ID,
+			})
+			if err != nil || retried == 0 {
+				return err
+			}
+
+			return enqueueWebhookDelivery(r.Context(), q, deliveryID)
+		})
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "error while retrying delivery", err)
+			return
+		}
+
+		// only dead deliveries can be retried; the rest are still trying or done
+		if retried == 0 {
+			respondWithError(w, http.StatusNotFound, "no dead delivery with that ID", nil)
+			return
+		}
+
+		respondWithJSON(w, http.StatusAccepted, nil)
+	}
+}
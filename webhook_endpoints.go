@@ -0,0 +1,270 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"time"
+
+	"github.com/adi290491/chirpy/internal/database"
+	"github.com/adi290491/chirpy/internal/webhook"
+	"github.com/google/uuid"
+)
+
+const (
+	maxWebhookEndpoints = 10
+	maxWebhookURLLength = 2048
+)
+
+// Events that can be subscribed to.
+const (
+	eventChirpCreated = "chirp.created"
+	eventChirpDeleted = "chirp.deleted"
+	eventUserFollowed = "user.followed"
+	eventUserUpgraded = "user.upgraded"
+)
+
+var webhookEvents = []string{eventChirpCreated, eventChirpDeleted, eventUserFollowed, eventUserUpgraded}
+
+type WebhookEndpoint struct {
+	ID        uuid.UUID `json:"id"`
+	URL       string    `json:"url"`
+	Events    []string  `json:"events"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	Secret    string    `json:"secret,omitempty"`
+}
+
+func webhookEndpointFromDB(row database.WebhookEndpoint) WebhookEndpoint {
+	return WebhookEndpoint{
+		ID:        row.ID,
+		URL:       row.Url,
+		Events:    row.Events,
+		CreatedAt: row.CreatedAt,
+		UpdatedAt: row.UpdatedAt,
+	}
+}
+
+// webhookPayload is the body of every delivery. ID is the same for all
+// endpoints that receive one event, so receivers can drop repeats.
+type webhookPayload struct {
+	ID        uuid.UUID `json:"id"`
+	Event     string    `json:"event"`
+	CreatedAt time.Time `json:"created_at"`
+	Data      any       `json:"data"`
+}
+
+// emitEvent queues event for delivery in the caller's transaction, so it is
+// sent if and only if the change it describes commits. userID is who the
+// event is about; their endpoints receive it along with the admin ones.
+func emitEvent(ctx context.Context, q *database.Queries, event string, userID uuid.UUID, data any) error {
+	payload, err := json.Marshal(webhookPayload{
+		ID:        uuid.New(),
+		Event:     event,
+		CreatedAt: time.Now().UTC(),
+		Data:      data,
+	})
+	if err != nil {
+		return err
+	}
+
+	deliveries, err := q.EnqueueWebhookDeliveries(ctx, database.EnqueueWebhookDeliveriesParams{
+		Event:   event,
+		Payload: payload,
+		UserID:  userID,
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, id := range deliveries {
+		if err := enqueueWebhookDelivery(ctx, q, id); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// webhookOwner picks whose endpoints a handler manages. Users manage their
+// own; admin endpoints have no owner.
+type webhookOwner func(r *http.Request) uuid.NullUUID
+
+func userWebhooks(r *http.Request) uuid.NullUUID {
+	return uuid.NullUUID{UUID: currentUserID(r), Valid: true}
+}
+
+func adminWebhooks(*http.Request) uuid.NullUUID {
+	return uuid.NullUUID{}
+}
+
+func (c *apiConfig) initWebhooks() {
+	// in dev, endpoints may point at a receiver on this machine
+	c.webhookClient = webhook.NewClient(webhookSendTimeout, c.PLATFORM == "dev")
+}
+
+func (c *apiConfig) validateWebhookURL(raw string) error {
+	if len(raw) > maxWebhookURLLength {
+		return fmt.Errorf("url must be at most %d characters", maxWebhookURLLength)
+	}
+
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" {
+		return errors.New("url must be absolute")
+	}
+
+	if u.Scheme != "https" && !(u.Scheme == "http" && c.PLATFORM == "dev") {
+		return errors.New("url must use https")
+	}
+
+	if u.User != nil {
+		return errors.New("url must not contain credentials")
+	}
+
+	return nil
+}
+
+func (c *apiConfig) CreateWebhookEndpoint(owner webhookOwner) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		type requestParams struct {
+			URL    string   `json:"url"`
+			Events []string `json:"events"`
+		}
+
+		var req requestParams
+		decoder := json.NewDecoder(r.Body)
+		err := decoder.Decode(&req)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "invalid request body", err)
+			return
+		}
+
+		err = c.validateWebhookURL(req.URL)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "invalid url", err)
+			return
+		}
+
+		if len(req.Events) == 0 {
+			respondWithError(w, http.StatusBadRequest, "at least one event is required", nil)
+			return
+		}
+
+		events := []string{}
+		for _, event := range req.Events {
+			if !slices.Contains(webhookEvents, event) {
+				respondWithError(w, http.StatusBadRequest, "invalid event", fmt.Errorf("unknown event %q", event))
+				return
+			}
+			if !slices.Contains(events, event) {
+				events = append(events, event)
+			}
+		}
+
+		count, err := c.db.CountWebhookEndpointsByOwner(r.Context(), owner(r))
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "error while creating webhook", err)
+			return
+		}
+
+		if count >= maxWebhookEndpoints {
+			respondWithError(w, http.StatusConflict, "too many webhooks", fmt.Errorf("at most %d webhooks can be registered", maxWebhookEndpoints))
+			return
+		}
+
+		secret, err := webhook.NewSecret()
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "error while generating secret", err)
+			return
+		}
+
+		row, err := c.db.CreateWebhookEndpoint(r.Context(), database.CreateWebhookEndpointParams{
+			UserID: owner(r),
+			Url:    req.URL,
+			Secret: secret,
+			Events: events,
+		})
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "error while creating webhook", err)
+			return
+		}
+
+		// the secret is only ever shown here
+		resp := webhookEndpointFromDB(row)
+		resp.Secret = secret
+		respondWithJSON(w, http.StatusCreated, resp)
+	}
+}
+
+func (c *apiConfig) ListWebhookEndpoints(owner webhookOwner) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		rows, err := c.db.ListWebhookEndpointsByOwner(r.Context(), owner(r))
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "error while fetching webhooks", err)
+			return
+		}
+
+		endpoints := []WebhookEndpoint{}
+		for _, row := range rows {
+			endpoints = append(endpoints, webhookEndpointFromDB(row))
+		}
+
+		respondWithJSON(w, http.StatusOK, endpoints)
+	}
+}
+
+func (c *apiConfig) DeleteWebhookEndpoint(owner webhookOwner) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := uuid.Parse(r.PathValue("id"))
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "invalid webhook ID", err)
+			return
+		}
+
+		deleted, err := c.db.DeleteWebhookEndpoint(r.Context(), database.DeleteWebhookEndpointParams{
+			ID:     id,
+			UserID: owner(r),
+		})
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "error while deleting webhook", err)
+			return
+		}
+
+		if deleted == 0 {
+			respondWithError(w, http.StatusNotFound, "webhook not found", nil)
+			return
+		}
+
+		respondWithJSON(w, http.StatusNoContent, nil)
+	}
+}
+
+// ownedWebhookEndpoint loads the endpoint named in the path if owner may see
+// it, answering the request itself when not.
+func (c *apiConfig) ownedWebhookEndpoint(w http.ResponseWriter, r *http.Request, owner webhookOwner) (database.WebhookEndpoint, bool) {
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "invalid webhook ID", err)
+		return database.WebhookEndpoint{}, false
+	}
+
+	endpoint, err := c.db.GetWebhookEndpointByOwner(r.Context(), database.GetWebhookEndpointByOwnerParams{
+		ID:     id,
+		UserID: owner(r),
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, http.StatusNotFound, "webhook not found", nil)
+		return database.WebhookEndpoint{}, false
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "error while fetching webhook", err)
+		return database.WebhookEndpoint{}, false
+	}
+
+	return endpoint, true
+}
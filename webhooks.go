@@ -1,68 +1,186 @@
 package main
 
 import (
+	"crypto/subtle"
+	"database/sql"
 	"encoding/json"
+	"errors"
+	"io"
+	"log"
 	"net/http"
+	"time"
 
 	"github.com/adi290491/chirpy/internal/auth"
 	"github.com/adi290491/chirpy/internal/database"
+	"github.com/adi290491/chirpy/internal/webhook"
 	"github.com/google/uuid"
 )
 
+const (
+	polkaProvider           = "polka"
+	polkaSignatureHeader    = "Polka-Signature"
+	polkaSignatureTolerance = 5 * time.Minute
+	maxWebhookBodySize      = 64 << 10
+)
+
+var (
+	errWebhooksNotConfigured = errors.New("no Polka secret is configured")
+	errAPIKeyMismatch        = errors.New("api key does not match")
+	errDuplicateEvent        = errors.New("event was already processed")
+	errWebhookUserNotFound   = errors.New("user not found")
+)
+
 type Webhook struct {
+	ID    string `json:"id"`
 	Event string `json:"event"`
-	Data  Data   `json:"data"`
+	// CreatedAt is when the event happened at Polka. Events can arrive out
+	// of order, so one older than the last applied to the subscription is
+	// recorded but not applied.
+	CreatedAt *time.Time `json:"created_at"`
+	Data      Data       `json:"data"`
 }
 
 type Data struct {
-	UserID uuid.UUID `json:"user_id"`
+	UserID           uuid.UUID  `json:"user_id"`
+	Plan             string     `json:"plan"`
+	CurrentPeriodEnd *time.Time `json:"current_period_end"`
 }
 
-var eventType = map[string]struct{}{
-	"user.upgraded": {},
+var eventType = map[string]polkaEventHandler{
+	"user.upgraded":        activateSubscription,
+	"subscription.renewed": activateSubscription,
+	"payment.failed":       markSubscriptionPastDue,
+	"user.downgraded":      endSubscription(subscriptionCanceled),
+	"subscription.expired": endSubscription(subscriptionExpired),
 }
 
-func (c *apiConfig) RunWebhook(w http.ResponseWriter, r *http.Request) {
+// initPolka refuses to start with only the static Polka API key outside
+// dev: it is sent as is with every request, so anyone who sees one can forge
+// events forever.
+func (c *apiConfig) initPolka() {
+	if c.polkaSecret != "" || c.API_KEY == "" {
+		return
+	}
 
-	apiKey, err := auth.GetAPIKey(r.Header)
+	if c.PLATFORM != "dev" {
+		log.Fatal("POLKA_KEY alone is only accepted with PLATFORM=dev; set POLKA_WEBHOOK_SECRET to verify Polka webhooks")
+	}
+	log.Print("warning: POLKA_WEBHOOK_SECRET is not set, so Polka webhooks are checked against the static POLKA_KEY only")
+}
+
+// verifyPolkaRequest checks the HMAC signature over the raw body. In dev,
+// without a signing secret, it falls back to the static API key.
+func (c *apiConfig) verifyPolkaRequest(r *http.Request, body []byte) error {
+	if c.polkaSecret != "" {
+		return webhook.Verify([]byte(c.polkaSecret), r.Header.Get(polkaSignatureHeader), body, time.Now(), polkaSignatureTolerance)
+	}
 
+	if c.API_KEY == "" || c.PLATFORM != "dev" {
+		return errWebhooksNotConfigured
+	}
+
+	apiKey, err := auth.GetAPIKey(r.Header)
 	if err != nil {
-		respondWithError(w, http.StatusUnauthorized, "", err)
-		return
+		return err
 	}
 
-	if apiKey != c.API_KEY {
-		respondWithError(w, http.StatusUnauthorized, "api key does not match", nil)
-		return
+	if subtle.ConstantTimeCompare([]byte(apiKey), []byte(c.API_KEY)) != 1 {
+		return errAPIKeyMismatch
 	}
 
-	var webhook Webhook
-	decoder := json.NewDecoder(r.Body)
-	err = decoder.Decode(&webhook)
+	return nil
+}
+
+func (c *apiConfig) RunWebhook(w http.ResponseWriter, r *http.Request) {
 
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxWebhookBodySize))
 	if err != nil {
-		respondWithError(w, http.StatusInternalServerError, "error while processing request", err)
+		respondWithError(w, http.StatusBadRequest, "error while reading request", err)
 		return
 	}
 
-	if _, ok := eventType[webhook.Event]; !ok {
-		respondWithError(w, http.StatusNoContent, "unrecognized event", nil)
+	err = c.verifyPolkaRequest(r, body)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "invalid webhook signature", err)
 		return
 	}
 
-	user, err := c.db.GetUserByID(r.Context(), webhook.Data.UserID)
+	var hook Webhook
+	err = json.Unmarshal(body, &hook)
 	if err != nil {
-		respondWithError(w, http.StatusNotFound, "error while fetching user", err)
+		respondWithError(w, http.StatusBadRequest, "error while processing request", err)
+		return
+	}
+
+	if hook.ID == "" {
+		respondWithError(w, http.StatusBadRequest, "event id is required", nil)
 		return
 	}
 
-	err = c.db.UpdateUserSubscription(r.Context(), database.UpdateUserSubscriptionParams{
-		IsChirpyRed: true,
-		ID:          user.ID,
+	// recording the event and applying it commit together, so a redelivery
+	// either finds the record or finds nothing applied
+	err = c.withTx(r.Context(), func(q *database.Queries) error {
+		recorded, err := q.RecordWebhookEvent(r.Context(), database.RecordWebhookEventParams{
+			Provider: polkaProvider,
+			ID:       hook.ID,
+			Event:    hook.Event,
+		})
+		if err != nil {
+			return err
+		}
+		if recorded == 0 {
+			return errDuplicateEvent
+		}
+
+		apply, ok := eventType[hook.Event]
+		if !ok {
+			return nil
+		}
+
+		_, err = q.GetUserByID(r.Context(), hook.Data.UserID)
+		if errors.Is(err, sql.ErrNoRows) {
+			return errWebhookUserNotFound
+		}
+		if err != nil {
+			return err
+		}
+
+		if hook.CreatedAt == nil {
+			return apply(r.Context(), q, hook.Data)
+		}
+
+		stale, err := subscriptionEventIsStale(r.Context(), q, hook.Data.UserID, *hook.CreatedAt)
+		if err != nil {
+			return err
+		}
+		if stale {
+			log.Printf("ignoring %s event %s for user %s: a newer event was already applied", hook.Event, hook.ID, hook.Data.UserID)
+			return nil
+		}
+
+		err = apply(r.Context(), q, hook.Data)
+		if err != nil {
+			return err
+		}
+
+		return q.RecordSubscriptionEvent(r.Context(), database.RecordSubscriptionEventParams{
+			UserID:     hook.Data.UserID,
+			OccurredAt: hook.CreatedAt.UTC(),
+		})
 	})
 
+	if errors.Is(err, errDuplicateEvent) {
+		respondWithJSON(w, http.StatusNoContent, nil)
+		return
+	}
+
+	if errors.Is(err, errWebhookUserNotFound) {
+		respondWithError(w, http.StatusNotFound, "error while fetching user", err)
+		return
+	}
+
 	if err != nil {
-		respondWithError(w, http.StatusNotFound, "error while updating user subscription", err)
+		respondWithError(w, http.StatusInternalServerError, "error while updating user subscription", err)
 		return
 	}
 
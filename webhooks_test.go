@@ -0,0 +1,156 @@
+package main
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/adi290491/chirpy/internal/database"
+	"github.com/adi290491/chirpy/internal/webhook"
+)
+
+const testPolkaSecret = "polka-secret"
+
+// subscriptionStore is the part of the database a Polka event touches.
+type subscriptionStore struct {
+	user database.User
+	sub  database.Subscription
+}
+
+// newSubscriptionStore returns a Chirpy Red member whose subscription runs
+// until periodEnd and last heard from Polka at lastEventAt.
+func newSubscriptionStore(periodEnd, lastEventAt time.Time) *subscriptionStore {
+	user := testUser()
+	user.IsChirpyRed = true
+
+	return &subscriptionStore{
+		user: user,
+		sub: database.Subscription{
+			UserID:           user.ID,
+			Plan:             defaultPlan,
+			Status:           subscriptionActive,
+			CurrentPeriodEnd: periodEnd,
+			CreatedAt:        user.CreatedAt,
+			UpdatedAt:        user.UpdatedAt,
+			LastEventAt:      sql.NullTime{Time: lastEventAt, Valid: true},
+		},
+	}
+}
+
+func (s *subscriptionStore) queries() map[string]fakeQuery {
+	return map[string]fakeQuery{
+		"RecordWebhookEvent": func([]driver.Value) ([][]driver.Value, error) {
+			return [][]driver.Value{{}}, nil
+		},
+		"GetUserByID": func([]driver.Value) ([][]driver.Value, error) {
+			return modelRows(s.user), nil
+		},
+		"GetSubscriptionByUserForUpdate": func([]driver.Value) ([][]driver.Value, error) {
+			return modelRows(s.sub), nil
+		},
+		"SetSubscriptionStatus": func(args []driver.Value) ([][]driver.Value, error) {
+			s.sub.Status = args[1].(string)
+			return [][]driver.Value{{}}, nil
+		},
+		"UpdateUserSubscription": func(args []driver.Value) ([][]driver.Value, error) {
+			s.user.IsChirpyRed = args[0].(bool)
+			return nil, nil
+		},
+		"RecordSubscriptionEvent": func(args []driver.Value) ([][]driver.Value, error) {
+			s.sub.LastEventAt = sql.NullTime{Time: args[1].(time.Time), Valid: true}
+			return nil, nil
+		},
+	}
+}
+
+func sendPolkaEvent(c *apiConfig, body string) *httptest.ResponseRecorder {
+	signature := webhook.Sign([]byte(testPolkaSecret), time.Now(), []byte(body))
+	header := http.Header{polkaSignatureHeader: {signature}}
+	return serve(c.RunWebhook, http.MethodPost, "/api/polka/webhooks", body, header)
+}
+
+func TestLateDowngradeIsIgnored(t *testing.T) {
+	renewedAt := time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name string
+		body string
+	}{
+		{
+			name: "older event",
+			body: `{"id":"evt_1","event":"user.downgraded","created_at":"2024-01-31T12:00:00Z","data":{"user_id":"%s"}}`,
+		},
+		{
+			name: "renewed period",
+			body: `{"id":"evt_1","event":"user.downgraded","data":{"user_id":"%s","current_period_end":"2024-02-01T00:00:00Z"}}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := newSubscriptionStore(renewedAt.Add(subscriptionPeriod), renewedAt)
+			c := newTestConfig(t, store.queries())
+			c.polkaSecret = testPolkaSecret
+
+			body := fmt.Sprintf(tt.body, store.user.ID)
+			if w := sendPolkaEvent(c, body); w.Code != http.StatusNoContent {
+				t.Fatalf("expected 204, got %d: %s", w.Code, w.Body)
+			}
+
+			if !store.user.IsChirpyRed || store.sub.Status != subscriptionActive {
+				t.Errorf("expected the renewed subscription to stay active, got %s (red %v)", store.sub.Status, store.user.IsChirpyRed)
+			}
+		})
+	}
+}
+
+func TestDowngradeEndsSubscription(t *testing.T) {
+	store := newSubscriptionStore(
+		time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC),
+		time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC),
+	)
+	c := newTestConfig(t, store.queries())
+	c.polkaSecret = testPolkaSecret
+
+	body := `{"id":"evt_2","event":"user.downgraded","created_at":"2024-02-10T00:00:00Z","data":{"user_id":"` + store.user.ID.String() + `","current_period_end":"2024-03-01T00:00:00Z"}}`
+	if w := sendPolkaEvent(c, body); w.Code != http.StatusNoContent {
+		t.Fatalf("expected 204, got %d: %s", w.Code, w.Body)
+	}
+
+	if store.user.IsChirpyRed || store.sub.Status != subscriptionCanceled {
+		t.Errorf("expected the subscription to be canceled, got %s (red %v)", store.sub.Status, store.user.IsChirpyRed)
+	}
+	if want := time.Date(2024, 2, 10, 0, 0, 0, 0, time.UTC); !store.sub.LastEventAt.Time.Equal(want) {
+		t.Errorf("expected the event time to be recorded, got %s", store.sub.LastEventAt.Time)
+	}
+}
+
+func TestPolkaAPIKeyOnlyInDev(t *testing.T) {
+	body := `{"id":"evt_3","event":"unknown.event","data":{}}`
+	header := http.Header{"Authorization": {"ApiKey polka-key"}}
+
+	for _, tt := range []struct {
+		platform string
+		want     int
+	}{
+		{"dev", http.StatusNoContent},
+		{"", http.StatusUnauthorized},
+	} {
+		c := newTestConfig(t, map[string]fakeQuery{
+			"RecordWebhookEvent": func([]driver.Value) ([][]driver.Value, error) {
+				return [][]driver.Value{{}}, nil
+			},
+		})
+		c.API_KEY = "polka-key"
+		c.PLATFORM = tt.platform
+
+		w := serve(c.RunWebhook, http.MethodPost, "/api/polka/webhooks", body, header)
+		if w.Code != tt.want {
+			t.Errorf("platform %q: expected %d, got %d", tt.platform, tt.want, w.Code)
+		}
+	}
+}